@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func runAddModule(args []string) error {
+	var (
+		configPath string
+		homepage   string
+		repository string
+		opts       addVersionOptions
+	)
+	fs := flag.NewFlagSet("add-module", flag.ExitOnError)
+	fs.StringVar(&opts.ModulesDir, "modules_dir", "modules", "The path to the modules directory.")
+	fs.StringVar(&opts.Module, "module", "", "The name of the module to add.")
+	fs.StringVar(&opts.Version, "version", "", "The initial version of the module.")
+	fs.StringVar(&opts.Archive, "archive", "", "The local copy of the source archive, used to compute integrity.")
+	fs.StringVar(&opts.URL, "url", "", "The URL that the source archive is published at.")
+	fs.StringVar(&opts.StripPrefix, "strip_prefix", "", "The archive prefix to strip. Computed from the archive if empty.")
+	fs.StringVar(&configPath, "config", "", "The config file with maintainer defaults. Defaults to "+defaultConfigPath())
+	fs.StringVar(&homepage, "homepage", "", "The module's home page. Defaults to the repository URL.")
+	fs.StringVar(&repository, "repository", "", `The module's repository, e.g. "github:owner/repo". Derived from --url if empty.`)
+	fs.Parse(args)
+	for name, value := range map[string]string{"module": opts.Module, "version": opts.Version, "archive": opts.Archive, "url": opts.URL} {
+		if value == "" {
+			return fmt.Errorf("flag --%s=... is required", name)
+		}
+	}
+	config, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	if repository == "" {
+		repository = githubRepository(opts.URL)
+		if repository == "" {
+			return fmt.Errorf("could not derive the repository from %q, use --repository=...", opts.URL)
+		}
+	}
+	if homepage == "" {
+		homepage = repoURL(repository)
+	}
+	return addModule(opts, Metadata{
+		Homepage:       homepage,
+		Maintainers:    config.Maintainers,
+		Repo:           []string{repository},
+		Versions:       []string{},
+		YankedVersions: map[string]string{},
+	})
+}
+
+// addModule creates a new module with the given metadata and its initial
+// version. Nothing is left behind if the result does not validate.
+func addModule(opts addVersionOptions, metadata Metadata) (err error) {
+	modulePath := filepath.Join(opts.ModulesDir, opts.Module)
+	if _, err := os.Stat(modulePath); err == nil {
+		return fmt.Errorf("module %s already exists, use add-version", opts.Module)
+	}
+	if err := os.MkdirAll(modulePath, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", modulePath, err)
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(modulePath)
+		}
+	}()
+
+	content, err := marshalJSON(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata.json: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(modulePath, "metadata.json"), content, 0644); err != nil {
+		return fmt.Errorf("failed to write metadata.json: %w", err)
+	}
+	if err := addVersion(opts); err != nil {
+		return err
+	}
+
+	module, err := loadModule(opts.ModulesDir, opts.Module)
+	if err != nil {
+		return err
+	}
+	if err := validateModule(module); err != nil {
+		return fmt.Errorf("module %s does not validate:\n%w", opts.Module, err)
+	}
+	log.Printf("added module %s", opts.Module)
+	return nil
+}
+
+// githubRepository derives the "github:owner/repo" repository from the URL
+// of a source archive hosted on GitHub.
+func githubRepository(archiveURL string) string {
+	u, err := url.Parse(archiveURL)
+	if err != nil || u.Host != "github.com" {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return "github:" + parts[0] + "/" + parts[1]
+}
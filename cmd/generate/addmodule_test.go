@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddModule(t *testing.T) {
+	dir := t.TempDir()
+	modulesDir := filepath.Join(dir, "modules")
+	archive := filepath.Join(dir, "v1.0.0.tar.gz")
+	writeTestArchive(t, archive, map[string]string{
+		"newmod-1.0.0/MODULE.bazel": "module(\n    name = \"newmod\",\n    version = \"1.0.0\",\n)\n",
+	})
+	configPath := filepath.Join(dir, "config.json")
+	writeTestFile(t, configPath, `{"maintainers": [{"name": "Jane Doe", "github": "jdoe"}]}`)
+	config, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := addVersionOptions{
+		ModulesDir: modulesDir,
+		Module:     "newmod",
+		Version:    "1.0.0",
+		Archive:    archive,
+		URL:        "https://github.com/jdoe/newmod/archive/refs/tags/v1.0.0.tar.gz",
+	}
+	repo := githubRepository(opts.URL)
+	if repo != "github:jdoe/newmod" {
+		t.Errorf("githubRepository() = %q", repo)
+	}
+
+	// No maintainers: the module does not validate and is removed.
+	err = addModule(opts, Metadata{Homepage: repoURL(repo), Repo: []string{repo}})
+	if err == nil {
+		t.Errorf("expected a validation error for a module without maintainers")
+	}
+	if _, err := os.Stat(filepath.Join(modulesDir, "newmod")); !os.IsNotExist(err) {
+		t.Errorf("expected the invalid module to be removed, got: %v", err)
+	}
+
+	err = addModule(opts, Metadata{
+		Homepage:    repoURL(repo),
+		Maintainers: config.Maintainers,
+		Repo:        []string{repo},
+	})
+	if err != nil {
+		t.Fatalf("addModule failed: %v", err)
+	}
+	m, err := loadModule(modulesDir, "newmod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Versions) != 1 || m.Metadata.Maintainers[0].GitHub != "jdoe" || m.Metadata.Versions[0] != "1.0.0" {
+		t.Errorf("unexpected module: %+v", m)
+	}
+	if _, err := os.Stat(filepath.Join(modulesDir, "newmod", "1.0.0", "patches")); !os.IsNotExist(err) {
+		t.Errorf("expected no patches when the archive version matches")
+	}
+
+	if err := addModule(opts, m.Metadata); err == nil {
+		t.Errorf("expected an error when adding an existing module")
+	}
+}
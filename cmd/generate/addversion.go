@@ -0,0 +1,337 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const versionPatchFile = "module_dot_bazel_version.patch"
+
+var (
+	moduleCallRe = regexp.MustCompile(`(?ms)^module\s*\((.*?)\)`)
+	// The name and version attributes of the module() call. Unlike nameRe
+	// and versionRe, they do not match repo_name or an empty version.
+	moduleNameAttrRe    = regexp.MustCompile(`\bname\s*=\s*"([^"]*)"`)
+	moduleVersionAttrRe = regexp.MustCompile(`\bversion\s*=\s*"([^"]*)"`)
+)
+
+func runAddVersion(args []string) error {
+	var (
+		modulesDir  string
+		module      string
+		version     string
+		archive     string
+		archiveURL  string
+		stripPrefix string
+		fromVersion string
+	)
+	fs := flag.NewFlagSet("add-version", flag.ExitOnError)
+	fs.StringVar(&modulesDir, "modules_dir", "modules", "The path to the modules directory.")
+	fs.StringVar(&module, "module", "", "The name of the module to add a version to.")
+	fs.StringVar(&version, "version", "", "The version to add.")
+	fs.StringVar(&archive, "archive", "", "The local copy of the source archive, used to compute integrity.")
+	fs.StringVar(&archiveURL, "url", "", "The URL that the source archive is published at.")
+	fs.StringVar(&stripPrefix, "strip_prefix", "", "The archive prefix to strip. Computed from the archive if empty.")
+	fs.StringVar(&fromVersion, "from_version", "", "The version to copy files from. Defaults to the closest older version.")
+	fs.Parse(args)
+	for name, value := range map[string]string{"module": module, "version": version, "archive": archive, "url": archiveURL} {
+		if value == "" {
+			return fmt.Errorf("flag --%s=... is required", name)
+		}
+	}
+	return addVersion(addVersionOptions{
+		ModulesDir:  modulesDir,
+		Module:      module,
+		Version:     version,
+		Archive:     archive,
+		URL:         archiveURL,
+		StripPrefix: stripPrefix,
+		FromVersion: fromVersion,
+	})
+}
+
+type addVersionOptions struct {
+	ModulesDir  string
+	Module      string
+	Version     string
+	Archive     string
+	URL         string
+	StripPrefix string
+	FromVersion string
+}
+
+func addVersion(opts addVersionOptions) error {
+	modulePath := filepath.Join(opts.ModulesDir, opts.Module)
+	metadataPath := filepath.Join(modulePath, "metadata.json")
+	metadata, err := readJSONObject(metadataPath)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata for module %q: %w", opts.Module, err)
+	}
+
+	versionPath := filepath.Join(modulePath, opts.Version)
+	if _, err := os.Stat(versionPath); err == nil {
+		return fmt.Errorf("version %s of %s already exists", opts.Version, opts.Module)
+	}
+
+	fromVersion := opts.FromVersion
+	if fromVersion == "" {
+		fromVersion, err = previousVersion(modulePath, opts.Version)
+		if err != nil {
+			return err
+		}
+	}
+	var fromPath string
+	if fromVersion != "" {
+		fromPath = filepath.Join(modulePath, fromVersion)
+	}
+
+	content, err := os.ReadFile(opts.Archive)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	files, err := archiveFiles(opts.Archive, content)
+	if err != nil {
+		return fmt.Errorf("failed to read archive %s: %w", opts.Archive, err)
+	}
+	stripPrefix := opts.StripPrefix
+	if stripPrefix == "" {
+		stripPrefix = commonPrefix(files)
+	}
+
+	source := Source{
+		URL:         opts.URL,
+		Integrity:   integrity(content),
+		StripPrefix: stripPrefix,
+	}
+
+	var moduleFile string
+	patches := make(map[string]string)
+	if upstream, ok := files[path.Join(stripPrefix, "MODULE.bazel")]; ok {
+		moduleFile, err = setModuleVersion(string(upstream), opts.Version)
+		if err != nil {
+			return err
+		}
+		if patch := unifiedDiff("a/MODULE.bazel", "b/MODULE.bazel", string(upstream), moduleFile); patch != "" {
+			patches[versionPatchFile] = patch
+		}
+	} else if fromPath != "" {
+		log.Printf("archive has no MODULE.bazel, copying it from version %s", fromVersion)
+		previous, err := os.ReadFile(filepath.Join(fromPath, "MODULE.bazel"))
+		if err != nil {
+			return fmt.Errorf("failed to read MODULE.bazel of version %s: %w", fromVersion, err)
+		}
+		moduleFile, err = setModuleVersion(string(previous), opts.Version)
+		if err != nil {
+			return err
+		}
+	} else {
+		return fmt.Errorf("archive has no MODULE.bazel and there is no previous version to copy it from")
+	}
+
+	var versions []string
+	if err := metadata.Get("versions", &versions); err != nil {
+		return err
+	}
+	if !slices.Contains(versions, opts.Version) {
+		versions = append(versions, opts.Version)
+		sortVersions(versions)
+	}
+	if err := metadata.Set("versions", versions); err != nil {
+		return err
+	}
+
+	// The version is written into a temporary directory, which is renamed
+	// once complete, so that a failure leaves nothing behind.
+	tmpPath, err := os.MkdirTemp(modulePath, "."+opts.Version+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create a directory for the version: %w", err)
+	}
+	defer os.RemoveAll(tmpPath)
+	if err := writeVersionFiles(tmpPath, fromPath, moduleFile, patches, source); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, versionPath); err != nil {
+		return fmt.Errorf("failed to create %s: %w", versionPath, err)
+	}
+	if err := writeJSONObject(metadataPath, metadata); err != nil {
+		os.RemoveAll(versionPath)
+		return fmt.Errorf("failed to write metadata.json: %w", err)
+	}
+	log.Printf("added %s@%s in %s", opts.Module, opts.Version, versionPath)
+	return nil
+}
+
+// writeVersionFiles writes the files of a new version into versionPath:
+// MODULE.bazel, the patches, source.json, and presubmit.yml copied from the
+// version at fromPath, if any.
+func writeVersionFiles(versionPath, fromPath, moduleFile string, patches map[string]string, source Source) error {
+	if err := os.WriteFile(filepath.Join(versionPath, "MODULE.bazel"), []byte(moduleFile), 0644); err != nil {
+		return fmt.Errorf("failed to write MODULE.bazel: %w", err)
+	}
+	if len(patches) > 0 {
+		source.Patches = make(map[string]string)
+		source.PatchStrip = 1
+		if err := os.MkdirAll(filepath.Join(versionPath, "patches"), 0755); err != nil {
+			return fmt.Errorf("failed to create patches directory: %w", err)
+		}
+		for name, patch := range patches {
+			if err := os.WriteFile(filepath.Join(versionPath, "patches", name), []byte(patch), 0644); err != nil {
+				return fmt.Errorf("failed to write patch %s: %w", name, err)
+			}
+			source.Patches[name] = integrity([]byte(patch))
+		}
+	}
+	sourceContent, err := marshalJSON(source)
+	if err != nil {
+		return fmt.Errorf("failed to encode source.json: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(versionPath, "source.json"), sourceContent, 0644); err != nil {
+		return fmt.Errorf("failed to write source.json: %w", err)
+	}
+	if fromPath != "" {
+		presubmit, err := os.ReadFile(filepath.Join(fromPath, "presubmit.yml"))
+		if err == nil {
+			err = os.WriteFile(filepath.Join(versionPath, "presubmit.yml"), presubmit, 0644)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to copy presubmit.yml: %w", err)
+		}
+	}
+	return nil
+}
+
+// previousVersion returns the newest version of the module that is older
+// than version, or the newest version if there is no older one.
+func previousVersion(modulePath, version string) (string, error) {
+	versions, err := findVersions(newRegistryFS(modulePath, nil), ".")
+	if err != nil {
+		return "", err
+	}
+	// findVersions sorts newest first.
+	for _, v := range versions {
+		if compareVersions(v.Name, version) < 0 {
+			return v.Name, nil
+		}
+	}
+	if len(versions) > 0 {
+		return versions[0].Name, nil
+	}
+	return "", nil
+}
+
+// setModuleVersion rewrites the version attribute of the module() call in a
+// MODULE.bazel file, adding the attribute after the name if it is missing.
+func setModuleVersion(moduleFile, version string) (string, error) {
+	loc := moduleCallRe.FindStringSubmatchIndex(moduleFile)
+	if loc == nil {
+		return "", fmt.Errorf("MODULE.bazel has no module() call")
+	}
+	args := moduleFile[loc[2]:loc[3]]
+	if moduleVersionAttrRe.MatchString(args) {
+		args = moduleVersionAttrRe.ReplaceAllLiteralString(args, fmt.Sprintf(`version = "%s"`, version))
+	} else {
+		nameLoc := moduleNameAttrRe.FindStringIndex(args)
+		if nameLoc == nil {
+			return "", fmt.Errorf("module() call has no name")
+		}
+		// Assume that the attributes are laid out one per line.
+		args = args[:nameLoc[1]] + fmt.Sprintf(",\n    version = \"%s\"", version) + args[nameLoc[1]:]
+	}
+	return moduleFile[:loc[2]] + args + moduleFile[loc[3]:], nil
+}
+
+// archiveFiles returns the regular files in a .tar.gz, .tgz, .tar or .zip
+// archive, keyed by their path.
+func archiveFiles(name string, content []byte) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		r, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range r.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			b, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+			files[path.Clean(f.Name)] = b
+		}
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"), strings.HasSuffix(name, ".tar"):
+		var r io.Reader = bytes.NewReader(content)
+		if !strings.HasSuffix(name, ".tar") {
+			gz, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			defer gz.Close()
+			r = gz
+		}
+		tr := tar.NewReader(r)
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if h.Typeflag != tar.TypeReg {
+				continue
+			}
+			b, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			files[path.Clean(h.Name)] = b
+		}
+	default:
+		return nil, fmt.Errorf("unsupported archive type")
+	}
+	return files, nil
+}
+
+// commonPrefix returns the top level directory shared by all files, or an
+// empty string if there is none.
+func commonPrefix(files map[string][]byte) string {
+	prefix := ""
+	for name := range files {
+		top, _, ok := strings.Cut(name, "/")
+		if !ok || (prefix != "" && top != prefix) {
+			return ""
+		}
+		prefix = top
+	}
+	return prefix
+}
+
+// integrity computes the subresource integrity value of content.
+func integrity(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256-" + base64.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTestArchive(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	gz.Close()
+	writeTestFile(t, path, buf.String())
+}
+
+func TestAddVersion(t *testing.T) {
+	dir := t.TempDir()
+	modulesDir := filepath.Join(dir, "modules")
+	writeTestFile(t, filepath.Join(modulesDir, "mod", "metadata.json"), `{
+    "homepage": "https://example.com",
+    "versions": [
+        "0.9.0",
+        "0.10.0"
+    ],
+    "yanked_versions": {}
+}
+`)
+	for _, v := range []string{"0.9.0", "0.10.0"} {
+		writeTestFile(t, filepath.Join(modulesDir, "mod", v, "MODULE.bazel"), `module(name = "mod", version = "`+v+`")`+"\n")
+		writeTestFile(t, filepath.Join(modulesDir, "mod", v, "source.json"), "{}\n")
+		writeTestFile(t, filepath.Join(modulesDir, "mod", v, "presubmit.yml"), "# "+v+"\n")
+	}
+	archive := filepath.Join(dir, "v0.11.0.tar.gz")
+	writeTestArchive(t, archive, map[string]string{
+		"mod-0.11.0/MODULE.bazel": "module(\n    name = \"mod\",\n    version = \"0.0.0\",\n)\n",
+		"mod-0.11.0/BUILD.bazel":  "",
+	})
+
+	err := addVersion(addVersionOptions{
+		ModulesDir: modulesDir,
+		Module:     "mod",
+		Version:    "0.11.0",
+		Archive:    archive,
+		URL:        "https://example.com/v0.11.0.tar.gz",
+	})
+	if err != nil {
+		t.Fatalf("addVersion failed: %v", err)
+	}
+
+	versionPath := filepath.Join(modulesDir, "mod", "0.11.0")
+	moduleFile, _ := os.ReadFile(filepath.Join(versionPath, "MODULE.bazel"))
+	if !strings.Contains(string(moduleFile), `version = "0.11.0"`) {
+		t.Errorf("MODULE.bazel version was not bumped:\n%s", moduleFile)
+	}
+	presubmit, _ := os.ReadFile(filepath.Join(versionPath, "presubmit.yml"))
+	if string(presubmit) != "# 0.10.0\n" {
+		t.Errorf("expected presubmit.yml copied from 0.10.0, got: %q", presubmit)
+	}
+	patch, err := os.ReadFile(filepath.Join(versionPath, "patches", versionPatchFile))
+	if err != nil || !strings.Contains(string(patch), `+    version = "0.11.0",`) {
+		t.Errorf("unexpected version patch: %v\n%s", err, patch)
+	}
+
+	versions, err := findVersions(newRegistryFS(modulesDir, nil), "mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := versions[0].Source
+	if versions[0].Name != "0.11.0" || source.StripPrefix != "mod-0.11.0" || source.PatchStrip != 1 ||
+		!strings.HasPrefix(source.Integrity, "sha256-") || source.Patches[versionPatchFile] != integrity(patch) {
+		t.Errorf("unexpected source.json: %+v", source)
+	}
+
+	metadata, err := readJSONObject(filepath.Join(modulesDir, "mod", "metadata.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	metadata.Get("versions", &got)
+	if want := []string{"0.9.0", "0.10.0", "0.11.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("metadata versions = %v, want %v", got, want)
+	}
+
+	if err := addVersion(addVersionOptions{ModulesDir: modulesDir, Module: "mod", Version: "0.11.0", Archive: archive}); err == nil {
+		t.Errorf("expected an error when adding an existing version")
+	}
+}
+
+func TestSetModuleVersion(t *testing.T) {
+	got, err := setModuleVersion("# module(name = \"x\")\nmodule(\n    name = \"m\",\n)\n\nbazel_dep(name = \"a\", version = \"1\")\n", "2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# module(name = \"x\")\nmodule(\n    name = \"m\",\n    version = \"2.0\",\n)\n\nbazel_dep(name = \"a\", version = \"1\")\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSetModuleVersionAttributes(t *testing.T) {
+	for _, test := range []struct{ name, in, want string }{
+		{"empty version", `module(name = "m", version = "")`, `module(name = "m", version = "2.0")`},
+		{"missing version", "module(\n    repo_name = \"r\",\n    name = \"m\",\n)", "module(\n    repo_name = \"r\",\n    name = \"m\",\n    version = \"2.0\",\n)"},
+	} {
+		got, err := setModuleVersion(test.in, "2.0")
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got != test.want {
+			t.Errorf("%s: got:\n%s\nwant:\n%s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAddVersionListed(t *testing.T) {
+	dir := t.TempDir()
+	modulesDir := filepath.Join(dir, "modules")
+	writeTestFile(t, filepath.Join(modulesDir, "mod", "metadata.json"), `{"versions": ["1.0.0"]}`)
+	archive := filepath.Join(dir, "v1.0.0.tar.gz")
+	writeTestArchive(t, archive, map[string]string{"mod/MODULE.bazel": `module(name = "mod", version = "1.0.0")`})
+
+	if err := addVersion(addVersionOptions{ModulesDir: modulesDir, Module: "mod", Version: "1.0.0", Archive: archive}); err != nil {
+		t.Fatalf("addVersion failed: %v", err)
+	}
+	metadata, err := readJSONObject(filepath.Join(modulesDir, "mod", "metadata.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	metadata.Get("versions", &got)
+	if want := []string{"1.0.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("metadata versions = %v, want %v", got, want)
+	}
+}
+
+func TestAddVersionCleansUp(t *testing.T) {
+	dir := t.TempDir()
+	modulesDir := filepath.Join(dir, "modules")
+	writeTestFile(t, filepath.Join(modulesDir, "mod", "metadata.json"), `{"versions": ["1.0.0"]}`)
+	writeTestFile(t, filepath.Join(modulesDir, "mod", "1.0.0", "MODULE.bazel"), `module(name = "mod", version = "1.0.0")`)
+	writeTestFile(t, filepath.Join(modulesDir, "mod", "1.0.0", "source.json"), "{}\n")
+	// presubmit.yml cannot be copied.
+	if err := os.MkdirAll(filepath.Join(modulesDir, "mod", "1.0.0", "presubmit.yml"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(dir, "v2.0.0.tar.gz")
+	writeTestArchive(t, archive, map[string]string{"mod/MODULE.bazel": `module(name = "mod", version = "2.0.0")`})
+
+	if err := addVersion(addVersionOptions{ModulesDir: modulesDir, Module: "mod", Version: "2.0.0", Archive: archive}); err == nil {
+		t.Fatalf("expected addVersion to fail")
+	}
+	entries, err := os.ReadDir(filepath.Join(modulesDir, "mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name() != "1.0.0" && e.Name() != "metadata.json" {
+			t.Errorf("expected the failed version to leave nothing behind, found %s", e.Name())
+		}
+	}
+}
+
+func TestArchiveFilesZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"./mod-1.0/MODULE.bazel", "./mod-1.0/BUILD.bazel"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(name))
+	}
+	zw.Close()
+
+	files, err := archiveFiles("a.zip", buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := files["mod-1.0/MODULE.bazel"]; !ok {
+		t.Errorf("expected the entry names to be cleaned, got %v", files)
+	}
+	if got := commonPrefix(files); got != "mod-1.0" {
+		t.Errorf("commonPrefix() = %q, want mod-1.0", got)
+	}
+}
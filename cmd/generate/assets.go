@@ -0,0 +1,146 @@
+package main
+
+import (
+	"embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+
+	"github.com/filmil/bazel-registry/third_party/web"
+)
+
+// assets holds the stylesheets and scripts of the generated pages.
+//
+//go:embed assets
+var assets embed.FS
+
+// vendoredAssets are the assets that the pages load from a CDN, unless they
+// are self-contained, by name, with their paths in web.FS.
+var vendoredAssets = map[string]string{
+	"bootstrap.min.css":           "bootstrap/dist/css/bootstrap.min.css",
+	"bootstrap.bundle.min.js":     "bootstrap/dist/js/bootstrap.bundle.min.js",
+	"bootstrap-icons.min.css":     "bootstrap-icons/font/bootstrap-icons.min.css",
+	"fonts/bootstrap-icons.woff":  "bootstrap-icons/font/fonts/bootstrap-icons.woff",
+	"fonts/bootstrap-icons.woff2": "bootstrap-icons/font/fonts/bootstrap-icons.woff2",
+}
+
+// fontURLRe matches the fonts that a stylesheet refers to, relative to it.
+var fontURLRe = regexp.MustCompile(`url\("(fonts/[^"?]+)(?:\?[^"]*)?"\)`)
+
+// readAsset returns the content of the named asset.
+func readAsset(name string) ([]byte, error) {
+	if p, ok := vendoredAssets[name]; ok {
+		return web.FS.ReadFile(p)
+	}
+	return assets.ReadFile(path.Join("assets", name))
+}
+
+// inlineFonts replaces the fonts that a stylesheet refers to with data URLs,
+// so that it can be inlined into a page.
+func inlineFonts(css []byte) ([]byte, error) {
+	var err error
+	css = fontURLRe.ReplaceAllFunc(css, func(m []byte) []byte {
+		name := string(fontURLRe.FindSubmatch(m)[1])
+		font, readErr := readAsset(name)
+		if readErr != nil {
+			err = readErr
+			return m
+		}
+		return fmt.Appendf(nil, `url("data:font/%s;base64,%s")`, path.Ext(name)[1:], base64.StdEncoding.EncodeToString(font))
+	})
+	return css, err
+}
+
+// SiteOptions control how the generated pages look and load their assets.
+type SiteOptions struct {
+	// SelfContained pages load nothing from other sites. They include
+	// Bootstrap and its icons from third_party/web.
+	SelfContained bool
+	// AssetsDir is the directory next to the index page that the assets are
+	// written to. If empty, the assets are inlined into each page.
+	AssetsDir string
+	// TemplateDir holds templates that replace the built-in ones, see
+	// parseTemplates.
+	TemplateDir string
+	Branding
+}
+
+// withDefaults fills in the branding that is not set.
+func (s SiteOptions) withDefaults() SiteOptions {
+	s.Branding = s.Branding.withDefaults()
+	return s
+}
+
+// Stylesheet returns the HTML that includes the named stylesheet into a
+// page, which is at root relative to the index page's directory.
+func (s SiteOptions) Stylesheet(root, name string) (template.HTML, error) {
+	if s.AssetsDir != "" {
+		return template.HTML(fmt.Sprintf(`<link rel="stylesheet" href="%s">`,
+			template.HTMLEscapeString(root+path.Join(s.AssetsDir, name)))), nil
+	}
+	content, err := readAsset(name)
+	if err != nil {
+		return "", err
+	}
+	content, err = inlineFonts(content)
+	if err != nil {
+		return "", err
+	}
+	return template.HTML("<style>\n" + string(content) + "</style>"), nil
+}
+
+// Script returns the HTML that includes the named script into a page, which
+// is at root relative to the index page's directory.
+func (s SiteOptions) Script(root, name string) (template.HTML, error) {
+	if s.AssetsDir != "" {
+		return template.HTML(fmt.Sprintf(`<script src="%s"></script>`,
+			template.HTMLEscapeString(root+path.Join(s.AssetsDir, name)))), nil
+	}
+	content, err := readAsset(name)
+	if err != nil {
+		return "", err
+	}
+	return template.HTML("<script>\n" + string(content) + "</script>"), nil
+}
+
+// writeAssets writes the assets into the assets directory next to the index
+// page in outputDir, if the pages link to them instead of inlining them.
+// The vendored assets are only written for self-contained pages.
+func writeAssets(outputDir string, site SiteOptions) error {
+	if site.AssetsDir == "" {
+		return nil
+	}
+	dir := filepath.Join(outputDir, filepath.FromSlash(site.AssetsDir))
+	entries, err := fs.ReadDir(assets, "assets")
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if site.SelfContained {
+		for name := range vendoredAssets {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		content, err := readAsset(name)
+		if err != nil {
+			return err
+		}
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(p, content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
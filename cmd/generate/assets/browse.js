@@ -0,0 +1,138 @@
+// Filters, sorts and groups the modules on the index page, by the query in
+// the search box and the filters below it. The state is kept in the query
+// string of the page, so that a view can be linked to.
+
+// filters are the facet filters, with the URL parameter that holds them.
+const filters = [
+  { id: 'tagFilter', param: 'tag', matches: (entry, value) => (entry.tags || []).includes(value) },
+  { id: 'maintainerFilter', param: 'maintainer', matches: (entry, value) => (entry.maintainer_keys || []).includes(value) },
+  { id: 'hostFilter', param: 'host', matches: (entry, value) => entry.host === value },
+  { id: 'statusFilter', param: 'status', matches: (entry, value) => entry.status === value },
+];
+
+// groupKeys return the group of a module, for each way of grouping.
+const groupKeys = {
+  category: entry => (entry.tags || [])[0] || '',
+  maintainer: entry => (entry.maintainer_keys || [])[0] || '',
+  host: entry => entry.host || '',
+  status: entry => entry.status,
+};
+
+function initBrowse() {
+  const indexElement = document.getElementById('search-index');
+  const container = document.getElementById('module-cards');
+  if (!indexElement || !container) {
+    return;
+  }
+  const searchInput = document.getElementById('searchInput');
+  const sortSelect = document.getElementById('sortSelect');
+  const groupSelect = document.getElementById('groupSelect');
+  const attestedFilter = document.getElementById('attestedFilter');
+  const count = document.getElementById('moduleCount');
+  const modules = [];
+  for (const entry of JSON.parse(indexElement.textContent)) {
+    const card = document.getElementById('card-' + entry.id);
+    if (card) {
+      modules.push({ entry, card });
+    }
+  }
+
+  const params = new URLSearchParams(location.search);
+  searchInput.value = params.get('q') || searchInput.value;
+  for (const filter of filters) {
+    document.getElementById(filter.id).value = params.get(filter.param) || '';
+  }
+  attestedFilter.checked = params.has('attested');
+  groupSelect.value = params.get('group') || '';
+  sortSelect.value = params.get('sort') || 'name';
+
+  // visible reports whether the module passes the filters.
+  function visible(entry) {
+    for (const filter of filters) {
+      const value = document.getElementById(filter.id).value;
+      if (value && !filter.matches(entry, value)) {
+        return false;
+      }
+    }
+    return !attestedFilter.checked || entry.attested;
+  }
+
+  // arrange sorts the cards, and groups them under a heading for each group
+  // with visible cards.
+  function arrange() {
+    container.querySelectorAll('.group-heading').forEach(heading => heading.remove());
+    const ordered = [...modules];
+    if (sortSelect.value === 'updated') {
+      ordered.sort((a, b) => Number(b.card.dataset.updated) - Number(a.card.dataset.updated));
+    }
+    const group = groupKeys[groupSelect.value];
+    if (group) {
+      // Modules without a group come last.
+      const key = m => group(m.entry) || '\uffff';
+      ordered.sort((a, b) => key(a) < key(b) ? -1 : key(a) > key(b) ? 1 : 0);
+    }
+    let current = null;
+    for (const m of ordered) {
+      if (group && m.card.style.display !== 'none' && group(m.entry) !== current) {
+        current = group(m.entry);
+        const heading = document.createElement('h4');
+        heading.className = 'group-heading mt-2 mb-3';
+        heading.textContent = current || 'Other';
+        container.appendChild(heading);
+      }
+      container.appendChild(m.card);
+    }
+  }
+
+  function saveState() {
+    const state = new URLSearchParams();
+    if (searchInput.value) {
+      state.set('q', searchInput.value);
+    }
+    for (const filter of filters) {
+      const value = document.getElementById(filter.id).value;
+      if (value) {
+        state.set(filter.param, value);
+      }
+    }
+    if (attestedFilter.checked) {
+      state.set('attested', '1');
+    }
+    if (groupSelect.value) {
+      state.set('group', groupSelect.value);
+    }
+    if (sortSelect.value !== 'name') {
+      state.set('sort', sortSelect.value);
+    }
+    const query = state.toString();
+    try {
+      history.replaceState(null, '', (query ? '?' + query : location.pathname) + location.hash);
+    } catch (e) {
+      // Some browsers do not allow this for pages opened from disk.
+    }
+  }
+
+  function update() {
+    const terms = parseQuery(searchInput.value);
+    let shown = 0;
+    for (const { entry, card } of modules) {
+      const hits = visible(entry) ? matchEntry(entry, terms) : null;
+      card.style.display = hits ? '' : 'none';
+      showHits(card, entry, hits || []);
+      if (hits) {
+        shown++;
+      }
+    }
+    arrange();
+    count.textContent = shown === modules.length ? `${shown} modules` : `${shown} of ${modules.length} modules`;
+    saveState();
+  }
+
+  searchInput.addEventListener('input', update);
+  for (const element of [sortSelect, groupSelect, attestedFilter, ...filters.map(f => document.getElementById(f.id))]) {
+    element.addEventListener('change', update);
+  }
+  update();
+}
+
+document.addEventListener('DOMContentLoaded', initBrowse);
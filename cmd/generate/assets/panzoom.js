@@ -0,0 +1,89 @@
+// Pans and zooms the dependency graph by changing the viewBox of its SVG.
+// Drag to pan, use the mouse wheel or the zoom buttons to zoom.
+function initPanZoom(svg) {
+  const initial = svg.viewBox.baseVal;
+  const home = { x: initial.x, y: initial.y, width: initial.width, height: initial.height };
+  let box = { ...home };
+
+  function apply() {
+    svg.setAttribute('viewBox', `${box.x} ${box.y} ${box.width} ${box.height}`);
+  }
+
+  // zoom scales the view by factor, keeping the point (cx, cy) of the graph
+  // in place.
+  function zoom(factor, cx, cy) {
+    box = {
+      x: cx - (cx - box.x) / factor,
+      y: cy - (cy - box.y) / factor,
+      width: box.width / factor,
+      height: box.height / factor,
+    };
+    apply();
+  }
+
+  function zoomAtCenter(factor) {
+    zoom(factor, box.x + box.width / 2, box.y + box.height / 2);
+  }
+
+  function toGraph(clientX, clientY) {
+    const point = svg.createSVGPoint();
+    point.x = clientX;
+    point.y = clientY;
+    return point.matrixTransform(svg.getScreenCTM().inverse());
+  }
+
+  svg.addEventListener('wheel', (event) => {
+    event.preventDefault();
+    const point = toGraph(event.clientX, event.clientY);
+    zoom(event.deltaY < 0 ? 1.2 : 1 / 1.2, point.x, point.y);
+  }, { passive: false });
+
+  let drag = null;
+  let dragged = false;
+  svg.addEventListener('pointerdown', (event) => {
+    drag = { x: event.clientX, y: event.clientY, box: { ...box }, scale: 1 / svg.getScreenCTM().a };
+    dragged = false;
+  });
+  svg.addEventListener('pointermove', (event) => {
+    if (!drag) {
+      return;
+    }
+    const dx = event.clientX - drag.x;
+    const dy = event.clientY - drag.y;
+    if (!dragged && Math.abs(dx) + Math.abs(dy) < 4) {
+      return;
+    }
+    dragged = true;
+    box.x = drag.box.x - dx * drag.scale;
+    box.y = drag.box.y - dy * drag.scale;
+    apply();
+  });
+  window.addEventListener('pointerup', () => {
+    drag = null;
+  });
+  // A drag that ends on a node does not follow its link.
+  svg.addEventListener('click', (event) => {
+    if (dragged) {
+      event.preventDefault();
+      dragged = false;
+    }
+  }, true);
+
+  return {
+    zoomIn: () => zoomAtCenter(1.2),
+    zoomOut: () => zoomAtCenter(1 / 1.2),
+    reset: () => {
+      box = { ...home };
+      apply();
+    },
+  };
+}
+
+window.panZoom = null;
+
+document.addEventListener('DOMContentLoaded', () => {
+  const svg = document.getElementById('dag-svg');
+  if (svg) {
+    window.panZoom = initPanZoom(svg);
+  }
+});
@@ -0,0 +1,86 @@
+function getPreferredTheme() {
+  const savedTheme = localStorage.getItem('theme');
+  if (savedTheme) {
+    return savedTheme;
+  }
+  return 'system';
+}
+
+function resolveTheme(theme) {
+  if (theme === 'system') {
+    const currentHour = new Date().getHours();
+    return (currentHour >= 19 || currentHour < 7) ? 'dark' : 'light';
+  }
+  return theme;
+}
+
+function updateThemeIcon(theme) {
+  const toggleBtn = document.getElementById('themeToggle');
+  if (toggleBtn) {
+    if (theme === 'light') {
+      toggleBtn.innerHTML = '<i class="bi bi-sun"></i>';
+    } else if (theme === 'dark') {
+      toggleBtn.innerHTML = '<i class="bi bi-moon-stars"></i>';
+    } else {
+      toggleBtn.innerHTML = '<i class="bi bi-circle-half"></i>';
+    }
+  }
+}
+
+function setTheme(theme) {
+  document.documentElement.setAttribute('data-bs-theme', resolveTheme(theme));
+  localStorage.setItem('theme', theme);
+  updateThemeIcon(theme);
+}
+
+setTheme(getPreferredTheme());
+
+document.addEventListener('DOMContentLoaded', () => {
+  updateThemeIcon(getPreferredTheme());
+});
+
+function toggleTheme() {
+  const currentTheme = getPreferredTheme();
+  let newTheme;
+  if (currentTheme === 'system') {
+    newTheme = 'light';
+  } else if (currentTheme === 'light') {
+    newTheme = 'dark';
+  } else {
+    newTheme = 'system';
+  }
+  setTheme(newTheme);
+}
+
+function copyToClipboard(text) {
+  navigator.clipboard.writeText(text).then(function() {
+    /* clipboard successfully set */
+  }, function() {
+    /* clipboard write failed */
+    alert('Failed to copy');
+  });
+}
+
+// timeAgo describes how long before now date was, e.g. "3 months ago".
+function timeAgo(date, now) {
+  const days = Math.floor((now - date) / (24 * 60 * 60 * 1000));
+  const plural = (n, unit) => n === 1 ? '1 ' + unit + ' ago' : n + ' ' + unit + 's ago';
+  if (days < 1) {
+    return 'today';
+  } else if (days < 30) {
+    return plural(days, 'day');
+  } else if (days < 365) {
+    return plural(Math.floor(days / 30), 'month');
+  }
+  return plural(Math.floor(days / 365), 'year');
+}
+
+document.addEventListener('DOMContentLoaded', () => {
+  const now = new Date();
+  document.querySelectorAll('time.time-ago').forEach((el) => {
+    const date = new Date(el.getAttribute('datetime'));
+    if (!isNaN(date)) {
+      el.textContent = timeAgo(date, now);
+    }
+  });
+});
@@ -0,0 +1,157 @@
+// Searches the modules on the index page, using the search index that the
+// generator embeds into the page.
+//
+// A query is a list of terms, all of which must match. A term like
+// "dep:rules_go" or "maintainer:jdoe" only matches the dependencies or the
+// maintainers of a module. Other terms match its name, description, tags,
+// maintainers, dependencies, versions or repositories, allowing for small
+// typos in longer terms. Restricted terms allow no typos.
+
+// searchFields are the fields of the search index, with the prefix that
+// restricts a term to the field.
+const searchFields = [
+  { field: 'name', prefix: 'name' },
+  { field: 'description', prefix: 'description' },
+  { field: 'tags', prefix: 'tag' },
+  { field: 'maintainers', prefix: 'maintainer' },
+  { field: 'deps', prefix: 'dep' },
+  { field: 'versions', prefix: 'version' },
+  { field: 'repos', prefix: 'repo' },
+];
+
+function parseQuery(query) {
+  const terms = [];
+  for (const word of query.toLowerCase().split(/\s+/)) {
+    if (!word) {
+      continue;
+    }
+    const colon = word.indexOf(':');
+    const restricted = colon > 0 && searchFields.find(f => f.prefix === word.slice(0, colon));
+    if (restricted) {
+      if (colon + 1 < word.length) {
+        terms.push({ field: restricted.field, text: word.slice(colon + 1) });
+      }
+    } else {
+      terms.push({ field: null, text: word });
+    }
+  }
+  return terms;
+}
+
+// editDistance is the number of insertions, deletions, substitutions and
+// transpositions of adjacent characters that turn a into b.
+function editDistance(a, b) {
+  const d = Array.from({ length: a.length + 1 }, (_, i) => [i]);
+  for (let j = 1; j <= b.length; j++) {
+    d[0][j] = j;
+  }
+  for (let i = 1; i <= a.length; i++) {
+    for (let j = 1; j <= b.length; j++) {
+      const cost = a[i - 1] === b[j - 1] ? 0 : 1;
+      d[i][j] = Math.min(d[i - 1][j] + 1, d[i][j - 1] + 1, d[i - 1][j - 1] + cost);
+      if (i > 1 && j > 1 && a[i - 1] === b[j - 2] && a[i - 2] === b[j - 1]) {
+        d[i][j] = Math.min(d[i][j], d[i - 2][j - 2] + 1);
+      }
+    }
+  }
+  return d[a.length][b.length];
+}
+
+// matchText returns the start and end of the part of text that term
+// matches, or null. Unless exact is set, terms of four or more characters
+// may be a word of text, or its beginning, with a typo or two.
+function matchText(term, text, exact) {
+  const lower = text.toLowerCase();
+  const i = lower.indexOf(term);
+  if (i >= 0) {
+    return [i, i + term.length];
+  }
+  if (exact || term.length < 4) {
+    return null;
+  }
+  const allowed = term.length < 10 ? 1 : 2;
+  const words = /[^\s,;:/()@<>"]+/g;
+  let m;
+  while ((m = words.exec(lower)) !== null) {
+    const word = m[0];
+    if (editDistance(term, word) <= allowed || editDistance(term, word.slice(0, term.length)) <= allowed) {
+      return [m.index, m.index + word.length];
+    }
+  }
+  return null;
+}
+
+// matchEntry returns where each term of the query matches the module, or
+// null if some term does not match.
+function matchEntry(entry, terms) {
+  const hits = [];
+  for (const term of terms) {
+    let hit = null;
+    for (const { field } of searchFields) {
+      if (term.field && term.field !== field) {
+        continue;
+      }
+      const values = [].concat(entry[field] || []);
+      for (const value of values) {
+        const range = matchText(term.text, value, term.field !== null);
+        if (range) {
+          hit = { field, value, range };
+          break;
+        }
+      }
+      if (hit) {
+        break;
+      }
+    }
+    if (!hit) {
+      return null;
+    }
+    hits.push(hit);
+  }
+  return hits;
+}
+
+function escapeHTML(s) {
+  return s.replace(/[&<>"']/g, c => `&#${c.charCodeAt(0)};`);
+}
+
+// highlight returns text as HTML, with the given ranges marked.
+function highlight(text, ranges) {
+  ranges = [...ranges].sort((a, b) => a[0] - b[0]);
+  let html = '';
+  let pos = 0;
+  for (const [start, end] of ranges) {
+    if (start < pos) {
+      continue;
+    }
+    html += escapeHTML(text.slice(pos, start)) + '<mark>' + escapeHTML(text.slice(start, end)) + '</mark>';
+    pos = end;
+  }
+  return html + escapeHTML(text.slice(pos));
+}
+
+// showHits highlights the matches in the name and description of a card,
+// and lists the matches in the other fields.
+function showHits(card, entry, hits) {
+  const ranges = field => hits.filter(h => h.field === field).map(h => h.range);
+  const name = card.querySelector('.module-name');
+  name.innerHTML = highlight(entry.name, ranges('name'));
+  const description = card.querySelector('.module-description');
+  if (description && entry.description) {
+    description.innerHTML = highlight(entry.description, ranges('description'));
+  }
+  const others = hits.filter(h => h.field !== 'name' && h.field !== 'description');
+  const list = card.querySelector('.search-hits');
+  list.innerHTML = others
+    .map(h => `${searchFields.find(f => f.field === h.field).prefix}: ${highlight(h.value, [h.range])}`)
+    .join(' &middot; ');
+  list.style.display = others.length ? '' : 'none';
+}
+
+// searchFor replaces the query in the search box.
+function searchFor(query) {
+  const searchInput = document.getElementById('searchInput');
+  searchInput.value = query;
+  searchInput.dispatchEvent(new Event('input'));
+  searchInput.scrollIntoView({ behavior: 'smooth', block: 'center' });
+}
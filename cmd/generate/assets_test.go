@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSelfContainedPages(t *testing.T) {
+	modulesDir := t.TempDir()
+	writeTestModule(t, modulesDir, "lib", map[string]string{
+		"1.0.0": `module(name = "lib", version = "1.0.0")`,
+	})
+	modules, err := findModules(modulesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	site := SiteOptions{SelfContained: true}
+
+	var buf bytes.Buffer
+	if err := writeHTML(testTemplates(t, ""), TemplateData{Site: site, Modules: modules, Graph: buildGraph(modules, cardLink, true).SVG()}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	outputDir := t.TempDir()
+	if err := writeModulePages(testTemplates(t, ""), modules, nil, outputDir, site); err != nil {
+		t.Fatal(err)
+	}
+	versionPage, err := os.ReadFile(filepath.Join(outputDir, "lib", "1.0.0", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, page := range map[string]string{"index": buf.String(), "version": string(versionPage)} {
+		for _, unwanted := range []string{"cdn.jsdelivr.net", "googletagmanager.com"} {
+			if strings.Contains(page, unwanted) {
+				t.Errorf("expected the %s page not to refer to %s", name, unwanted)
+			}
+		}
+		for _, want := range []string{"function toggleTheme()", "Bootstrap v5.3.3", "Bootstrap Icons v1.11.3", `url("data:font/woff2;base64,`, "bootstrap.bundle.min.js.map"} {
+			if !strings.Contains(page, want) {
+				t.Errorf("expected the %s page to inline its assets, with %q", name, want)
+			}
+		}
+	}
+}
+
+func TestAnalytics(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeHTML(testTemplates(t, ""), TemplateData{}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "googletagmanager.com") {
+		t.Errorf("expected no analytics by default")
+	}
+	buf.Reset()
+	if err := writeHTML(testTemplates(t, ""), TemplateData{Site: SiteOptions{Branding: Branding{AnalyticsID: "G-TEST"}}}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "googletagmanager.com/gtag/js?id=G-TEST") {
+		t.Errorf("expected the analytics tag with --analytics_id")
+	}
+}
+
+func TestAssetsDir(t *testing.T) {
+	site := SiteOptions{SelfContained: true, AssetsDir: "assets"}
+	outputDir := t.TempDir()
+	if err := writeAssets(outputDir, site); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"registry.css", "registry.js", "bootstrap.min.css", "bootstrap.bundle.min.js", "bootstrap-icons.min.css", "fonts/bootstrap-icons.woff2"} {
+		if _, err := os.Stat(filepath.Join(outputDir, "assets", filepath.FromSlash(name))); err != nil {
+			t.Errorf("expected %s to be written: %v", name, err)
+		}
+	}
+
+	if err := writeChangelogPage(testTemplates(t, ""), nil, false, outputDir, site); err != nil {
+		t.Fatal(err)
+	}
+	page, err := os.ReadFile(filepath.Join(outputDir, "changelog.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<link rel="stylesheet" href="assets/bootstrap.min.css">`,
+		`<link rel="stylesheet" href="assets/bootstrap-icons.min.css">`,
+		`<script src="assets/registry.js"></script>`,
+	} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("expected the page to contain %q", want)
+		}
+	}
+}
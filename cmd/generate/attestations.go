@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	attestationsFile      = "attestations.json"
+	attestationsMediaType = "application/vnd.build.bazel.registry.attestation+json;version=1.0.0"
+)
+
+// Attestations is the content of a version's attestations.json, as described
+// by the Bazel registry attestation schema.
+type Attestations struct {
+	MediaType    string                 `json:"mediaType"`
+	Attestations map[string]Attestation `json:"attestations"`
+}
+
+// Attestation points at the provenance attestation for a single artifact.
+type Attestation struct {
+	URL       string `json:"url"`
+	Integrity string `json:"integrity"`
+}
+
+// Artifacts returns the sorted names of the attested artifacts.
+func (a *Attestations) Artifacts() []string {
+	var names []string
+	for name := range a.Attestations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseAttestations parses and validates the content of attestations.json.
+func parseAttestations(content []byte, source Source) (*Attestations, error) {
+	var a Attestations
+	if err := json.Unmarshal(content, &a); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", attestationsFile, err)
+	}
+	if err := validateAttestations(&a, source); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// validateAttestations checks that every attested artifact has a URL and an
+// integrity, and that the attested artifacts are exactly the ones that the
+// version publishes: source.json, MODULE.bazel and the source archive.
+func validateAttestations(a *Attestations, source Source) error {
+	if a.MediaType != attestationsMediaType {
+		return fmt.Errorf("unsupported mediaType: %q", a.MediaType)
+	}
+	if len(a.Attestations) == 0 {
+		return fmt.Errorf("no attestations listed")
+	}
+	for _, name := range a.Artifacts() {
+		att := a.Attestations[name]
+		u, err := url.Parse(att.URL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("artifact %q: invalid url: %q", name, att.URL)
+		}
+		if err := checkIntegrity(att.Integrity); err != nil {
+			return fmt.Errorf("artifact %q: %w", name, err)
+		}
+	}
+
+	required := []string{"source.json", "MODULE.bazel"}
+	if source.URL != "" {
+		u, err := url.Parse(source.URL)
+		if err != nil {
+			return fmt.Errorf("invalid source.json url: %q", source.URL)
+		}
+		required = append(required, path.Base(u.Path))
+	}
+	for _, name := range required {
+		if _, ok := a.Attestations[name]; !ok {
+			return fmt.Errorf("missing attestation for %q", name)
+		}
+	}
+	if len(a.Attestations) != len(required) {
+		return fmt.Errorf("attested artifacts %v do not match published artifacts %v", a.Artifacts(), required)
+	}
+	return nil
+}
+
+// verifyAttestations checks that the attestations of version v are about
+// the artifacts that the version publishes: the in-toto statement of each
+// attestation must have a subject with the digest of source.json,
+// MODULE.bazel or the source archive, as in source.json. The attestations
+// are looked up in the archive cache, by their integrity in
+// attestations.json, and those not in the cache are not checked. Their
+// signatures are not checked either.
+func verifyAttestations(v Version, cacheDir string) error {
+	if v.Attestations == nil {
+		return nil
+	}
+	integrities := map[string]string{
+		"source.json":  integrity([]byte(v.SourceFile)),
+		"MODULE.bazel": integrity([]byte(v.ModuleFile)),
+	}
+	if u, err := url.Parse(v.Source.URL); err == nil {
+		integrities[path.Base(u.Path)] = v.Source.Integrity
+	}
+	var errs []error
+	for _, name := range v.Attestations.Artifacts() {
+		att := v.Attestations.Attestations[name]
+		file, err := findCachedArchive(cacheDir, Source{URL: att.URL, Integrity: att.Integrity})
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("artifact %q: %w", name, err))
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("artifact %q: %w", name, err))
+			continue
+		}
+		subjects, err := attestationSubjects(content)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("artifact %q: %s: %w", name, att.URL, err))
+			continue
+		}
+		if !attestsIntegrity(subjects, integrities[name]) {
+			errs = append(errs, fmt.Errorf("artifact %q: %s does not attest its integrity %s", name, att.URL, integrities[name]))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// inTotoSubject is a subject of an in-toto statement: an artifact, with its
+// digests as hex strings by algorithm.
+type inTotoSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// attestationSubjects returns the subjects of the in-toto statements in an
+// attestation file. Each line of the file is a DSSE envelope, either bare or
+// in a Sigstore bundle.
+func attestationSubjects(content []byte) ([]inTotoSubject, error) {
+	var subjects []inTotoSubject
+	for _, line := range splitLines(string(content)) {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var envelope struct {
+			Payload      string `json:"payload"`
+			DSSEEnvelope *struct {
+				Payload string `json:"payload"`
+			} `json:"dsseEnvelope"`
+		}
+		if err := json.Unmarshal([]byte(line), &envelope); err != nil {
+			return nil, fmt.Errorf("failed to parse attestation: %w", err)
+		}
+		payload := envelope.Payload
+		if envelope.DSSEEnvelope != nil {
+			payload = envelope.DSSEEnvelope.Payload
+		}
+		statement, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return nil, fmt.Errorf("malformed attestation payload: %w", err)
+		}
+		var s struct {
+			Subject []inTotoSubject `json:"subject"`
+		}
+		if err := json.Unmarshal(statement, &s); err != nil {
+			return nil, fmt.Errorf("failed to parse in-toto statement: %w", err)
+		}
+		subjects = append(subjects, s.Subject...)
+	}
+	if len(subjects) == 0 {
+		return nil, fmt.Errorf("no in-toto subjects")
+	}
+	return subjects, nil
+}
+
+// attestsIntegrity reports whether one of subjects has the digest of
+// integrity.
+func attestsIntegrity(subjects []inTotoSubject, integrity string) bool {
+	algo, digest, _ := strings.Cut(integrity, "-")
+	raw, err := base64.StdEncoding.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+	for _, s := range subjects {
+		if strings.EqualFold(s.Digest[algo], hex.EncodeToString(raw)) {
+			return true
+		}
+	}
+	return false
+}
+
+var integrityDigestSizes = map[string]int{
+	"sha256": 32,
+	"sha384": 48,
+	"sha512": 64,
+}
+
+// checkIntegrity verifies that s is a well-formed subresource integrity
+// value, e.g. "sha256-<base64 digest>".
+func checkIntegrity(s string) error {
+	algo, digest, ok := strings.Cut(s, "-")
+	if !ok {
+		return fmt.Errorf("malformed integrity: %q", s)
+	}
+	size, ok := integrityDigestSizes[algo]
+	if !ok {
+		return fmt.Errorf("unsupported integrity algorithm: %q", s)
+	}
+	raw, err := base64.StdEncoding.DecodeString(digest)
+	if err != nil || len(raw) != size {
+		return fmt.Errorf("malformed integrity digest: %q", s)
+	}
+	return nil
+}
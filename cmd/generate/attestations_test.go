@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testAttestations = `{
+    "mediaType": "application/vnd.build.bazel.registry.attestation+json;version=1.0.0",
+    "attestations": {
+        "source.json": {
+            "url": "https://example.com/v1.0.0/source.json.intoto.jsonl",
+            "integrity": "sha256-r8OAAxBZdAzdRnKhwxb7gIHvlrERw7yEyMf3My1SJbo="
+        },
+        "MODULE.bazel": {
+            "url": "https://example.com/v1.0.0/MODULE.bazel.intoto.jsonl",
+            "integrity": "sha256-qt0hW9zUHHlaBRVgEVsv2pd37K1LLJmKidOngLavTPE="
+        },
+        "v1.0.0.tar.gz": {
+            "url": "https://example.com/v1.0.0/v1.0.0.tar.gz.intoto.jsonl",
+            "integrity": "sha256-O4mSeOqoho84fg7kw46aTa372HhqChDNh5uu2RNLuoI="
+        }
+    }
+}`
+
+func TestParseAttestations(t *testing.T) {
+	source := Source{URL: "https://example.com/v1.0.0/v1.0.0.tar.gz"}
+	a, err := parseAttestations([]byte(testAttestations), source)
+	if err != nil {
+		t.Fatalf("parseAttestations failed: %v", err)
+	}
+	if got := strings.Join(a.Artifacts(), ","); got != "MODULE.bazel,source.json,v1.0.0.tar.gz" {
+		t.Errorf("unexpected artifacts: %v", got)
+	}
+
+	if _, err := parseAttestations([]byte(testAttestations), Source{URL: "https://example.com/v2.tar.gz"}); err == nil {
+		t.Errorf("expected an error for an archive that is not attested")
+	}
+}
+
+func TestFindVersionsAttestations(t *testing.T) {
+	modulesDir := t.TempDir()
+	writeTestModule(t, modulesDir, "lib", map[string]string{
+		"1.0.0": `module(name = "lib", version = "1.0.0")`,
+		"2.0.0": `module(name = "lib", version = "2.0.0")`,
+	})
+	writeTestFile(t, filepath.Join(modulesDir, "lib", "1.0.0", "source.json"), `{"url": "https://example.com/v1.0.0/v1.0.0.tar.gz"}`)
+	writeTestFile(t, filepath.Join(modulesDir, "lib", "1.0.0", attestationsFile), testAttestations)
+	m, err := loadModule(modulesDir, "lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Versions are sorted newest first.
+	if m.Versions[0].Attestations != nil || m.Versions[0].AttestationsFile != "" {
+		t.Errorf("expected no attestations for 2.0.0, got %+v", m.Versions[0].Attestations)
+	}
+	if m.Versions[1].Attestations == nil || m.Versions[1].AttestationsFile != testAttestations {
+		t.Errorf("expected the attestations of 1.0.0")
+	}
+}
+
+func TestValidateAttestations_Errors(t *testing.T) {
+	source := Source{URL: "https://example.com/a.tar.gz"}
+	valid := Attestation{
+		URL:       "https://example.com/x.intoto.jsonl",
+		Integrity: "sha256-r8OAAxBZdAzdRnKhwxb7gIHvlrERw7yEyMf3My1SJbo=",
+	}
+	tests := []struct {
+		name string
+		a    Attestations
+	}{
+		{"bad media type", Attestations{MediaType: "text/plain"}},
+		{"empty", Attestations{MediaType: attestationsMediaType}},
+		{"missing url", Attestations{
+			MediaType: attestationsMediaType,
+			Attestations: map[string]Attestation{
+				"source.json":  {Integrity: valid.Integrity},
+				"MODULE.bazel": valid,
+				"a.tar.gz":     valid,
+			},
+		}},
+		{"bad integrity", Attestations{
+			MediaType: attestationsMediaType,
+			Attestations: map[string]Attestation{
+				"source.json":  valid,
+				"MODULE.bazel": {URL: valid.URL, Integrity: "sha256-abc"},
+				"a.tar.gz":     valid,
+			},
+		}},
+		{"extra artifact", Attestations{
+			MediaType: attestationsMediaType,
+			Attestations: map[string]Attestation{
+				"source.json":  valid,
+				"MODULE.bazel": valid,
+				"a.tar.gz":     valid,
+				"b.tar.gz":     valid,
+			},
+		}},
+	}
+	for _, test := range tests {
+		if err := validateAttestations(&test.a, source); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+// testAttestation returns an attestation file whose in-toto statement has
+// subjects with the given sha256 digests, in a Sigstore bundle.
+func testAttestation(digests ...string) []byte {
+	var subjects []string
+	for _, d := range digests {
+		subjects = append(subjects, fmt.Sprintf(`{"name": "x", "digest": {"sha256": %q}}`, d))
+	}
+	statement := `{"_type": "https://in-toto.io/Statement/v1", "subject": [` + strings.Join(subjects, ",") + `]}`
+	return []byte(fmt.Sprintf(`{"dsseEnvelope": {"payloadType": "application/vnd.in-toto+json", "payload": %q}}`+"\n",
+		base64.StdEncoding.EncodeToString([]byte(statement))))
+}
+
+func TestVerifyAttestations(t *testing.T) {
+	cacheDir := t.TempDir()
+	v := Version{
+		ModuleFile: `module(name = "m", version = "1.0.0")`,
+		SourceFile: `{"url": "https://example.com/m.tar.gz"}`,
+		Source: Source{
+			URL:       "https://example.com/m.tar.gz",
+			Integrity: integrity([]byte("archive")),
+		},
+		Attestations: &Attestations{Attestations: map[string]Attestation{}},
+	}
+	hexDigest := func(content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+	attest := func(name string, content []byte) {
+		file := name + ".intoto.jsonl"
+		if err := os.WriteFile(filepath.Join(cacheDir, file), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		v.Attestations.Attestations[name] = Attestation{URL: "https://example.com/" + file, Integrity: integrity(content)}
+	}
+	attest("MODULE.bazel", testAttestation(hexDigest(v.ModuleFile)))
+	attest("m.tar.gz", testAttestation("00", hexDigest("archive")))
+	// Not in the cache, so not checked.
+	v.Attestations.Attestations["source.json"] = Attestation{URL: "https://example.com/gone.intoto.jsonl", Integrity: integrity([]byte("gone"))}
+	if err := verifyAttestations(v, cacheDir); err != nil {
+		t.Errorf("expected the attestations to verify, got: %v", err)
+	}
+
+	attest("source.json", testAttestation(hexDigest("other")))
+	err := verifyAttestations(v, cacheDir)
+	if err == nil || !strings.Contains(err.Error(), `artifact "source.json"`) || strings.Contains(err.Error(), "MODULE.bazel") {
+		t.Errorf("expected only source.json to fail, got: %v", err)
+	}
+}
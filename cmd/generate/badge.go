@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The colors of the badges, as on shields.io.
+const (
+	badgeBlue = "#007ec6"
+	badgeGrey = "#9f9f9f"
+	badgeRed  = "#e05d44"
+)
+
+const (
+	// badgeCharWidth is the average width of a character in Verdana at
+	// 11px, which the badges are set in.
+	badgeCharWidth = 6.5
+	badgePadding   = 6
+	badgeHeight    = 20
+)
+
+// badge is a shields.io style badge, with a label on the left and a message
+// on the right.
+type badge struct {
+	Label   string
+	Message string
+	Color   string
+}
+
+// badgeLink is the link from the index page to the badge of a module.
+func badgeLink(name string) string {
+	return "badges/" + name + ".svg"
+}
+
+// moduleBadge is the badge of a module, which shows its latest version that
+// is not yanked, and whether the module is deprecated.
+func moduleBadge(m Module, label string) badge {
+	b := badge{Label: label, Message: "no versions", Color: badgeGrey}
+	if len(m.Versions) > 0 {
+		b.Message, b.Color = "yanked", badgeRed
+	}
+	for _, v := range m.Versions {
+		if _, ok := m.Metadata.YankedVersions[v.Name]; !ok {
+			b.Message, b.Color = "v"+v.Name, badgeBlue
+			break
+		}
+	}
+	if m.Metadata.Deprecated != "" {
+		b.Message += ", deprecated"
+		b.Color = badgeGrey
+	}
+	return b
+}
+
+// SVG renders the badge in the flat style of shields.io. The widths of the
+// texts are estimated, and the texts are stretched to fit them.
+func (b badge) SVG() string {
+	labelText, messageText := template.HTMLEscapeString(b.Label), template.HTMLEscapeString(b.Message)
+	labelWidth := float64(len([]rune(b.Label)))*badgeCharWidth + 2*badgePadding
+	messageWidth := float64(len([]rune(b.Message)))*badgeCharWidth + 2*badgePadding
+	width := labelWidth + messageWidth
+	title := labelText + ": " + messageText
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%.1f" height="%d" role="img" aria-label="%s">`+"\n",
+		width, badgeHeight, title)
+	fmt.Fprintf(&sb, "<title>%s</title>\n", title)
+	sb.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>` + "\n")
+	fmt.Fprintf(&sb, `<clipPath id="r"><rect width="%.1f" height="%d" rx="3" fill="#fff"/></clipPath>`+"\n", width, badgeHeight)
+	fmt.Fprintf(&sb, `<g clip-path="url(#r)"><rect width="%.1f" height="%d" fill="#555"/><rect x="%.1f" width="%.1f" height="%d" fill="%s"/><rect width="%.1f" height="%d" fill="url(#s)"/></g>`+"\n",
+		labelWidth, badgeHeight, labelWidth, messageWidth, badgeHeight, b.Color, width, badgeHeight)
+	sb.WriteString(`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">` + "\n")
+	for _, t := range []struct {
+		x, width float64
+		text     string
+	}{
+		{labelWidth / 2, labelWidth - 2*badgePadding, labelText},
+		{labelWidth + messageWidth/2, messageWidth - 2*badgePadding, messageText},
+	} {
+		// The shadow, then the text.
+		fmt.Fprintf(&sb, `<text x="%.1f" y="15" fill="#010101" fill-opacity=".3" textLength="%.1f">%s</text>`, t.x, t.width, t.text)
+		fmt.Fprintf(&sb, `<text x="%.1f" y="14" textLength="%.1f">%s</text>`+"\n", t.x, t.width, t.text)
+	}
+	sb.WriteString("</g>\n</svg>\n")
+	return sb.String()
+}
+
+// writeBadges writes the badge of each module into
+// outputDir/badges/<module>.svg. It fails if any of the declared modules,
+// see declaredModules, was skipped.
+func writeBadges(modules []Module, declared []string, outputDir string, branding Branding) error {
+	if err := checkDeclared(modules, declared); err != nil {
+		return err
+	}
+	branding = branding.withDefaults()
+	if err := os.MkdirAll(filepath.Join(outputDir, "badges"), 0755); err != nil {
+		return err
+	}
+	for _, m := range modules {
+		svg := moduleBadge(m, branding.BadgeLabel).SVG()
+		if err := os.WriteFile(filepath.Join(outputDir, filepath.FromSlash(badgeLink(m.Name))), []byte(svg), 0644); err != nil {
+			return fmt.Errorf("module %s: %w", m.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestModuleBadge(t *testing.T) {
+	m := Module{
+		Name: "lib",
+		Metadata: Metadata{
+			YankedVersions: map[string]string{"1.1.0": "broken", "0.9.0": "broken"},
+		},
+		Versions: []Version{{Name: "1.1.0"}, {Name: "1.0.0"}, {Name: "0.9.0"}},
+	}
+	for _, test := range []struct {
+		name   string
+		module func(m Module) Module
+		want   badge
+	}{
+		{"latest not yanked", func(m Module) Module { return m }, badge{"reg", "v1.0.0", badgeBlue}},
+		{"deprecated", func(m Module) Module {
+			m.Metadata.Deprecated = "Use other."
+			return m
+		}, badge{"reg", "v1.0.0, deprecated", badgeGrey}},
+		{"all yanked", func(m Module) Module {
+			m.Versions = []Version{{Name: "1.1.0"}}
+			return m
+		}, badge{"reg", "yanked", badgeRed}},
+		{"no versions", func(m Module) Module {
+			m.Versions = nil
+			return m
+		}, badge{"reg", "no versions", badgeGrey}},
+	} {
+		if got := moduleBadge(test.module(m), "reg"); got != test.want {
+			t.Errorf("%s: moduleBadge() = %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestBadgeSVG(t *testing.T) {
+	svg := badge{"a <b>", "v1.0.0", badgeBlue}.SVG()
+	if err := xml.Unmarshal([]byte(svg), new(struct{})); err != nil {
+		t.Fatalf("expected the badge to be valid XML: %v\n%s", err, svg)
+	}
+	for _, want := range []string{`<title>a &lt;b&gt;: v1.0.0</title>`, `fill="#007ec6"`, `>v1.0.0</text>`} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("expected the badge to contain %q, got:\n%s", want, svg)
+		}
+	}
+}
+
+func TestWriteBadges(t *testing.T) {
+	outputDir := t.TempDir()
+	if err := writeBadges([]Module{testGraphModule("lib", "1.0.0")}, nil, outputDir, Branding{}); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join(outputDir, "badges", "lib.svg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<title>" + defaultBranding.BadgeLabel + ": v1.0.0</title>"; !strings.Contains(string(content), want) {
+		t.Errorf("expected the badge to contain %q, got:\n%s", want, content)
+	}
+
+	// A skipped module would leave its declared badge missing.
+	if err := writeBadges([]Module{testGraphModule("lib", "1.0.0")}, []string{"broken", "lib"}, outputDir, Branding{}); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("expected an error for the skipped module, got: %v", err)
+	}
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+func runExportBundle(args []string) error {
+	var (
+		opts        bundleOptions
+		overlayDirs stringsFlag
+	)
+	fs := flag.NewFlagSet("export-bundle", flag.ExitOnError)
+	fs.StringVar(&opts.ModulesDir, "modules_dir", "modules", "The path to the modules directory.")
+	fs.Var(&overlayDirs, "overlay_dir", "A directory of modules to merge over the modules directory. May be repeated.")
+	fs.StringVar(&opts.CacheDir, "cache_dir", "", "The archive cache: a Bazel repository cache, or a directory of downloaded archives.")
+	fs.StringVar(&opts.Output, "output", "", "The bundle directory to create, or a .tar.gz file to write the bundle to.")
+	fs.StringVar(&opts.BaseURL, "base_url", "", "The URL the bundle will be available at, such as file:///srv/bazel-registry. The source.json files point at the archives under it.")
+	fs.BoolVar(&opts.AllowMissing, "allow_missing", false, "Keep the original URLs of archives that are not in the cache, instead of failing.")
+	fs.Parse(args)
+	opts.OverlayDirs = overlayDirs
+	if opts.CacheDir == "" {
+		return fmt.Errorf("flag --cache_dir=... is required")
+	}
+	if opts.Output == "" {
+		return fmt.Errorf("flag --output=... is required")
+	}
+	if opts.BaseURL == "" {
+		return fmt.Errorf("flag --base_url=... is required")
+	}
+	return exportBundle(opts)
+}
+
+type bundleOptions struct {
+	ModulesDir   string
+	OverlayDirs  []string
+	CacheDir     string
+	Output       string
+	BaseURL      string
+	AllowMissing bool
+}
+
+// exportBundle writes a self-contained copy of the registry, including all
+// the source archives, which can be used as a file:// registry without
+// network access. The archives are named by their hash, and the source.json
+// files are rewritten to point at them.
+func exportBundle(opts bundleOptions) error {
+	// The bundle is written to a temporary path next to the output, which is
+	// renamed once complete, so that a failure leaves nothing behind.
+	w, tmpPath, err := newBundleWriter(opts.Output)
+	if err != nil {
+		return fmt.Errorf("failed to create the bundle: %w", err)
+	}
+	defer os.RemoveAll(tmpPath)
+	archives, err := writeBundle(w, opts)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if !isTarball(opts.Output) {
+		mode = 0755
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, opts.Output); err != nil {
+		return fmt.Errorf("failed to create %s: %w", opts.Output, err)
+	}
+	log.Printf("exported %d archives to %s, use it with --registry=%s", archives, opts.Output, strings.TrimSuffix(opts.BaseURL, "/"))
+	return nil
+}
+
+// writeBundle writes the registry into w, and returns the number of
+// archives in it.
+func writeBundle(w bundleWriter, opts bundleOptions) (int, error) {
+	baseURL := strings.TrimSuffix(opts.BaseURL, "/")
+
+	registryDir := filepath.Dir(opts.ModulesDir)
+	config, err := loadRegistryConfig(registryDir)
+	if err != nil {
+		return 0, err
+	}
+	// The mirrors are of no use offline.
+	config.Mirrors = []string{}
+	if config.ModuleBasePath == "" {
+		config.ModuleBasePath = "modules"
+	}
+	content, err := marshalJSON(config)
+	if err != nil {
+		return 0, err
+	}
+	if err := w.WriteFile(registryConfigFile, content); err != nil {
+		return 0, err
+	}
+
+	archives := make(map[string]bool)
+	var missing []string
+	fsys := newRegistryFS(opts.ModulesDir, opts.OverlayDirs)
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		if path.Base(name) == "source.json" {
+			var source Source
+			if err := json.Unmarshal(content, &source); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			archive, err := findCachedArchive(opts.CacheDir, source)
+			if errors.Is(err, fs.ErrNotExist) && opts.AllowMissing {
+				missing = append(missing, source.URL)
+				return w.WriteFile(path.Join("modules", name), content)
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			archiveName, err := bundleArchiveName(source)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if !archives[archiveName] {
+				archives[archiveName] = true
+				if err := w.CopyFile(archiveName, archive); err != nil {
+					return err
+				}
+			}
+			content, err = pointSourceAt(content, baseURL+"/"+archiveName)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		return w.WriteFile(path.Join("modules", name), content)
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, u := range missing {
+		log.Printf("warning: not in the archive cache, keeping the original URL: %s", u)
+	}
+	return len(archives), nil
+}
+
+// archiveExtensions are the archive types that Bazel can extract, longest
+// first.
+var archiveExtensions = []string{".tar.gz", ".tar.bz2", ".tar.xz", ".tar.zst", ".tgz", ".tbz", ".txz", ".tzst", ".tar", ".zip", ".jar", ".war", ".aar", ".deb", ".ar", ".7z"}
+
+// bundleArchiveName names an archive in the bundle by its hash, keeping the
+// extension, which Bazel uses to tell the archive type.
+func bundleArchiveName(source Source) (string, error) {
+	algo, digest, _ := strings.Cut(source.Integrity, "-")
+	raw, err := base64.StdEncoding.DecodeString(digest)
+	if err != nil {
+		return "", fmt.Errorf("malformed integrity: %q", source.Integrity)
+	}
+	ext, err := archiveExtension(source)
+	if err != nil {
+		return "", err
+	}
+	return "archives/" + algo + "-" + hex.EncodeToString(raw) + ext, nil
+}
+
+// archiveExtension returns the extension for the archive of source, from
+// archive_type if it is set, or from the url otherwise.
+func archiveExtension(source Source) (string, error) {
+	if source.ArchiveType != "" {
+		ext := "." + source.ArchiveType
+		if !slices.Contains(archiveExtensions, ext) {
+			return "", fmt.Errorf("unsupported archive_type: %q", source.ArchiveType)
+		}
+		return ext, nil
+	}
+	u, err := url.Parse(source.URL)
+	if err != nil {
+		return "", err
+	}
+	base := path.Base(u.Path)
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(base, ext) {
+			return ext, nil
+		}
+	}
+	return "", fmt.Errorf("cannot tell the archive type of %s, set archive_type in source.json", source.URL)
+}
+
+// findCachedArchive finds the archive of source in the cache directory and
+// checks its integrity. The cache can be a Bazel repository cache, or a
+// directory with the archives under the names they are downloaded as.
+func findCachedArchive(cacheDir string, source Source) (string, error) {
+	var candidates []string
+	if algo, digest, ok := strings.Cut(source.Integrity, "-"); ok && algo == "sha256" {
+		if raw, err := base64.StdEncoding.DecodeString(digest); err == nil {
+			candidates = append(candidates, filepath.Join(cacheDir, "content_addressable", "sha256", hex.EncodeToString(raw), "file"))
+		}
+	}
+	if u, err := url.Parse(source.URL); err == nil {
+		candidates = append(candidates, filepath.Join(cacheDir, path.Base(u.Path)))
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err != nil {
+			continue
+		}
+		ok, err := matchesIntegrity(candidate, source.Integrity)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("archive %s: %w", source.URL, fs.ErrNotExist)
+}
+
+func matchesIntegrity(file, integrity string) (bool, error) {
+	algo, digest, _ := strings.Cut(integrity, "-")
+	var h hash.Hash
+	switch algo {
+	case "sha256":
+		h = sha256.New()
+	case "sha384":
+		h = sha512.New384()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return false, fmt.Errorf("unsupported integrity: %q", integrity)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return false, err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)) == digest, nil
+}
+
+// pointSourceAt sets the url of a source.json, and drops its mirrors.
+func pointSourceAt(content []byte, archiveURL string) ([]byte, error) {
+	var source jsonObject
+	if err := json.Unmarshal(content, &source); err != nil {
+		return nil, err
+	}
+	if err := source.Set("url", archiveURL); err != nil {
+		return nil, err
+	}
+	source.Delete("mirror_urls")
+	return marshalJSON(&source)
+}
+
+func isTarball(name string) bool {
+	return strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz")
+}
+
+// bundleWriter writes the files of a bundle into a directory or a tarball.
+type bundleWriter interface {
+	WriteFile(name string, content []byte) error
+	CopyFile(name, src string) error
+	Close() error
+}
+
+// newBundleWriter returns a writer for a temporary directory or tarball next
+// to output, and its path.
+func newBundleWriter(output string) (bundleWriter, string, error) {
+	dir, pattern := filepath.Dir(output), "."+filepath.Base(output)+"-"
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, "", err
+	}
+	if !isTarball(output) {
+		tmpPath, err := os.MkdirTemp(dir, pattern)
+		if err != nil {
+			return nil, "", err
+		}
+		return dirBundleWriter(tmpPath), tmpPath, nil
+	}
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, "", err
+	}
+	gz := gzip.NewWriter(f)
+	return &tarBundleWriter{f: f, gz: gz, tw: tar.NewWriter(gz)}, f.Name(), nil
+}
+
+type dirBundleWriter string
+
+func (d dirBundleWriter) WriteFile(name string, content []byte) error {
+	p := filepath.Join(string(d), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(p, content, 0644)
+}
+
+func (d dirBundleWriter) CopyFile(name, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	p := filepath.Join(string(d), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func (d dirBundleWriter) Close() error { return nil }
+
+type tarBundleWriter struct {
+	f  *os.File
+	gz *gzip.Writer
+	tw *tar.Writer
+}
+
+func (t *tarBundleWriter) WriteFile(name string, content []byte) error {
+	if err := t.tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	_, err := t.tw.Write(content)
+	return err
+}
+
+func (t *tarBundleWriter) CopyFile(name, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := t.tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: info.Size(), Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	_, err = io.Copy(t.tw, f)
+	return err
+}
+
+func (t *tarBundleWriter) Close() error {
+	return errors.Join(t.tw.Close(), t.gz.Close(), t.f.Close())
+}
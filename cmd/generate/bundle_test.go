@@ -0,0 +1,143 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportBundle(t *testing.T) {
+	dir := t.TempDir()
+	modulesDir := filepath.Join(dir, "registry", "modules")
+	cacheDir := filepath.Join(dir, "cache")
+	archive := "archive content"
+	writeTestFile(t, filepath.Join(cacheDir, "v1.0.0.tar.gz"), archive)
+
+	writeTestModule(t, modulesDir, "mod", map[string]string{"1.0.0": `module(name = "mod", version = "1.0.0")`})
+	writeTestFile(t, filepath.Join(modulesDir, "mod", "1.0.0", "source.json"), `{
+    "integrity": "`+integrity([]byte(archive))+`",
+    "url": "https://example.com/mod/v1.0.0.tar.gz",
+    "mirror_urls": ["https://mirror.example.com/mod/v1.0.0.tar.gz"],
+    "patches": {"fix.patch": "sha256-abc"}
+}`)
+	writeTestFile(t, filepath.Join(modulesDir, "mod", "1.0.0", "patches", "fix.patch"), "--- a/x\n")
+
+	opts := bundleOptions{
+		ModulesDir: modulesDir,
+		CacheDir:   cacheDir,
+		Output:     filepath.Join(dir, "bundle"),
+		BaseURL:    "file:///srv/bundle/",
+	}
+	if err := exportBundle(opts); err != nil {
+		t.Fatalf("exportBundle failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(opts.Output, "modules", "mod", "1.0.0", "source.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var source map[string]interface{}
+	json.Unmarshal(content, &source)
+	url, _ := source["url"].(string)
+	if !strings.HasPrefix(url, "file:///srv/bundle/archives/") || !strings.HasSuffix(url, ".tar.gz") || source["mirror_urls"] != nil {
+		t.Errorf("unexpected source.json: %s", content)
+	}
+	archivePath := filepath.Join(opts.Output, filepath.FromSlash(strings.TrimPrefix(url, "file:///srv/bundle/")))
+	if got, err := os.ReadFile(archivePath); err != nil || string(got) != archive {
+		t.Errorf("expected the archive at %s, got: %q, %v", archivePath, got, err)
+	}
+	for _, name := range []string{registryConfigFile, "modules/mod/metadata.json", "modules/mod/1.0.0/patches/fix.patch"} {
+		if _, err := os.Stat(filepath.Join(opts.Output, name)); err != nil {
+			t.Errorf("expected %s in the bundle: %v", name, err)
+		}
+	}
+
+	// A missing archive fails the export, unless it is allowed.
+	os.Remove(filepath.Join(cacheDir, "v1.0.0.tar.gz"))
+	opts.Output = filepath.Join(dir, "bundle.tar.gz")
+	if err := exportBundle(opts); err == nil {
+		t.Errorf("expected an error for a missing archive")
+	}
+	// The failed export leaves nothing behind.
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 3 {
+		t.Errorf("expected only registry, cache and bundle in %s, got: %v, %v", dir, entries, err)
+	}
+	opts.AllowMissing = true
+	if err := exportBundle(opts); err != nil {
+		t.Fatalf("exportBundle failed: %v", err)
+	}
+	f, err := os.Open(opts.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, h.Name)
+	}
+	if len(names) != 5 {
+		t.Errorf("unexpected tarball content: %v", names)
+	}
+}
+
+func TestArchiveExtension(t *testing.T) {
+	for _, test := range []struct {
+		source Source
+		want   string
+	}{
+		{Source{URL: "https://example.com/v1.0.0.tar.gz"}, ".tar.gz"},
+		{Source{URL: "https://example.com/a.zip?raw=true"}, ".zip"},
+		{Source{URL: "https://example.com/archive/abc123", ArchiveType: "tar.gz"}, ".tar.gz"},
+		{Source{URL: "https://example.com/a.zip", ArchiveType: "tgz"}, ".tgz"},
+	} {
+		got, err := archiveExtension(test.source)
+		if err != nil || got != test.want {
+			t.Errorf("archiveExtension(%+v) = %q, %v, want %q", test.source, got, err, test.want)
+		}
+	}
+	for _, source := range []Source{
+		{URL: "https://example.com/archive/abc123"},
+		{URL: "https://example.com/archive/v1.0.0"},
+		{URL: "https://example.com/a.tar.gz", ArchiveType: "rar"},
+	} {
+		if got, err := archiveExtension(source); err == nil {
+			t.Errorf("archiveExtension(%+v) = %q, want an error", source, got)
+		}
+	}
+}
+
+func TestFindCachedArchive_RepositoryCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	content := []byte("archive")
+	source := Source{Integrity: integrity(content), URL: "https://example.com/a.zip"}
+	name, err := bundleArchiveName(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := strings.TrimSuffix(strings.TrimPrefix(name, "archives/sha256-"), ".zip")
+	if _, err := hex.DecodeString(digest); err != nil {
+		t.Fatalf("unexpected archive name: %s", name)
+	}
+	writeTestFile(t, filepath.Join(cacheDir, "content_addressable", "sha256", digest, "file"), string(content))
+	if _, err := findCachedArchive(cacheDir, source); err != nil {
+		t.Errorf("expected the archive in the repository cache: %v", err)
+	}
+}
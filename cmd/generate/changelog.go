@@ -0,0 +1,342 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
+)
+
+// The kinds of changelog events.
+const (
+	eventModule  = "module"
+	eventVersion = "version"
+	eventYank    = "yank"
+	eventUnyank  = "unyank"
+)
+
+// ChangeEvent is an entry of the registry changelog.
+type ChangeEvent struct {
+	Time    time.Time `json:"time"`
+	Kind    string    `json:"kind"`
+	Module  string    `json:"module"`
+	Version string    `json:"version,omitempty"`
+	// Detail is the yank reason for yank events.
+	Detail string `json:"detail,omitempty"`
+	Commit string `json:"commit"`
+}
+
+// ChangelogPageData is the data for the changelog page.
+type ChangelogPageData struct {
+	Title       string
+	Root        string
+	Site        SiteOptions
+	Events      []ChangeEvent
+	ModulePages bool
+}
+
+// whatsNewCount is the number of changelog events shown on the index page.
+const whatsNewCount = 10
+
+func runChangelog(args []string) error {
+	var (
+		modulesDir string
+		output     string
+	)
+	fs := flag.NewFlagSet("changelog", flag.ExitOnError)
+	fs.StringVar(&modulesDir, "modules_dir", "modules", "The path to the modules directory, in a git checkout.")
+	fs.StringVar(&output, "output", "", "The file to write the changelog to. Defaults to stdout.")
+	fs.Parse(args)
+
+	events, err := gitChangelog(modulesDir)
+	if err != nil {
+		return err
+	}
+	content, err := marshalJSON(events)
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		_, err := os.Stdout.Write(content)
+		return err
+	}
+	return os.WriteFile(output, content, 0644)
+}
+
+// gitChangelog reads the changelog of the registry from the git history of
+// modulesDir, newest first.
+func gitChangelog(modulesDir string) ([]ChangeEvent, error) {
+	repo, prefix, err := openGitRepository(modulesDir)
+	if err != nil {
+		return nil, err
+	}
+	if shallow, err := repo.Storer.Shallow(); err != nil {
+		return nil, err
+	} else if len(shallow) > 0 {
+		return nil, fmt.Errorf("%s is in a shallow clone, the changelog needs the full history", modulesDir)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	iter, err := repo.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		return nil, err
+	}
+	var commits []*object.Commit
+	err = iter.ForEach(func(c *object.Commit) error {
+		commits = append(commits, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(commits)
+
+	var events []ChangeEvent
+	for _, c := range commits {
+		// As in git log, merges do not list the files they changed.
+		if c.NumParents() > 1 {
+			continue
+		}
+		after, err := gitSubtree(c, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("commit %s: %w", c.Hash, err)
+		}
+		var before *object.Tree
+		if c.NumParents() == 1 {
+			parent, err := c.Parent(0)
+			if err != nil {
+				return nil, fmt.Errorf("commit %s: %w", c.Hash, err)
+			}
+			if before, err = gitSubtree(parent, prefix); err != nil {
+				return nil, fmt.Errorf("commit %s: %w", parent.Hash, err)
+			}
+		}
+		changes, err := object.DiffTree(before, after)
+		if err != nil {
+			return nil, fmt.Errorf("commit %s: %w", c.Hash, err)
+		}
+		var commitEvents []ChangeEvent
+		for _, change := range changes {
+			action, err := change.Action()
+			if err != nil {
+				return nil, fmt.Errorf("commit %s: %w", c.Hash, err)
+			}
+			parts := strings.Split(change.To.Name, "/")
+			switch {
+			case action == merkletrie.Insert && len(parts) == 2 && parts[1] == "metadata.json":
+				commitEvents = append(commitEvents, ChangeEvent{Kind: eventModule, Module: parts[0]})
+			case action == merkletrie.Insert && len(parts) == 3 && parts[2] == "source.json":
+				commitEvents = append(commitEvents, ChangeEvent{Kind: eventVersion, Module: parts[0], Version: parts[1]})
+			case action == merkletrie.Modify && len(parts) == 2 && parts[1] == "metadata.json":
+				yanks, err := gitYankEvents(before, after, parts[0])
+				if err != nil {
+					return nil, fmt.Errorf("commit %s: %w", c.Hash, err)
+				}
+				commitEvents = append(commitEvents, yanks...)
+			}
+		}
+		// New modules come before their first versions.
+		sort.SliceStable(commitEvents, func(i, j int) bool {
+			return commitEvents[i].Kind == eventModule && commitEvents[j].Kind != eventModule
+		})
+		for i := range commitEvents {
+			commitEvents[i].Time = c.Committer.When
+			commitEvents[i].Commit = c.Hash.String()
+		}
+		events = append(events, commitEvents...)
+	}
+	sortChangelog(events)
+	return events, nil
+}
+
+// openGitRepository opens the git repository that dir is in, and returns it
+// with the slash separated path of dir in the repository.
+func openGitRepository(dir string) (*git.Repository, string, error) {
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open the git repository of %s: %w", dir, err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, "", err
+	}
+	root, err := filepath.EvalSymlinks(worktree.Filesystem.Root())
+	if err != nil {
+		return nil, "", err
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	if abs, err = filepath.EvalSymlinks(abs); err != nil {
+		return nil, "", err
+	}
+	prefix, err := filepath.Rel(root, abs)
+	if err != nil {
+		return nil, "", err
+	}
+	return repo, filepath.ToSlash(prefix), nil
+}
+
+// gitSubtree returns the tree at prefix in commit c, or nil if there is
+// none.
+func gitSubtree(c *object.Commit, prefix string) (*object.Tree, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+	if prefix == "." {
+		return tree, nil
+	}
+	subtree, err := tree.Tree(prefix)
+	if errors.Is(err, object.ErrDirectoryNotFound) {
+		return nil, nil
+	}
+	return subtree, err
+}
+
+// gitYankEvents compares the yanked versions of a module before and after a
+// commit that modified its metadata.json.
+func gitYankEvents(before, after *object.Tree, module string) ([]ChangeEvent, error) {
+	beforeMetadata, err := gitMetadata(before, module)
+	if err != nil {
+		return nil, err
+	}
+	afterMetadata, err := gitMetadata(after, module)
+	if err != nil {
+		return nil, err
+	}
+	var events []ChangeEvent
+	for _, v := range sortedKeys(afterMetadata.YankedVersions) {
+		if _, ok := beforeMetadata.YankedVersions[v]; !ok {
+			events = append(events, ChangeEvent{Kind: eventYank, Module: module, Version: v, Detail: afterMetadata.YankedVersions[v]})
+		}
+	}
+	for _, v := range sortedKeys(beforeMetadata.YankedVersions) {
+		if _, ok := afterMetadata.YankedVersions[v]; !ok {
+			events = append(events, ChangeEvent{Kind: eventUnyank, Module: module, Version: v})
+		}
+	}
+	return events, nil
+}
+
+func gitMetadata(tree *object.Tree, module string) (Metadata, error) {
+	var metadata Metadata
+	f, err := tree.File(module + "/metadata.json")
+	if err != nil {
+		return metadata, fmt.Errorf("%s/metadata.json: %w", module, err)
+	}
+	content, err := f.Contents()
+	if err != nil {
+		return metadata, err
+	}
+	if err := json.Unmarshal([]byte(content), &metadata); err != nil {
+		return metadata, fmt.Errorf("failed to parse %s/metadata.json: %w", module, err)
+	}
+	return metadata, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortChangelog sorts events newest first, keeping the order of events
+// from the same commit.
+func sortChangelog(events []ChangeEvent) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.After(events[j].Time)
+	})
+}
+
+// loadChangelog reads a changelog written by the changelog subcommand.
+func loadChangelog(path string) ([]ChangeEvent, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var events []ChangeEvent
+	if err := json.Unmarshal(content, &events); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	sortChangelog(events)
+	return events, nil
+}
+
+// writeChangelogPage writes the changelog page into outputDir.
+func writeChangelogPage(tmpl *template.Template, events []ChangeEvent, modulePages bool, outputDir string, site SiteOptions) error {
+	site = site.withDefaults()
+	return writePage(outputDir, "changelog.html", tmpl, "changelog", ChangelogPageData{
+		Title:       "Changelog",
+		Site:        site,
+		Events:      events,
+		ModulePages: modulePages,
+	})
+}
+
+const changelogTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+{{template "head" .}}
+</head>
+<body>
+    <div class="container">
+		<div class="d-flex justify-content-between align-items-center mt-5">
+			<h1 class="mb-0">Changelog</h1>
+			{{template "themeToggle"}}
+		</div>
+		<p class="mt-2"><a href="index.html"><i class="bi bi-arrow-left"></i> All modules</a></p>
+		{{if .Events}}
+		{{template "changelogEvents" .}}
+		{{else}}
+		<p class="text-muted">No history is available.</p>
+		{{end}}
+    </div>
+    {{template "footer" .}}
+</body>
+</html>
+
+{{define "changelogEvents"}}
+		<table class="table table-sm">
+			{{$modulePages := .ModulePages}}
+			{{range .Events}}
+			<tr>
+				<td class="text-nowrap text-muted">{{.Time.Format "2006-01-02"}}</td>
+				<td>
+					{{if eq .Kind "module"}}
+						<span class="badge bg-primary">new module</span>
+						<a href="{{if $modulePages}}{{.Module}}/index.html{{else}}index.html#card-{{sanitizeID .Module}}{{end}}">{{.Module}}</a>
+					{{else if eq .Kind "version"}}
+						<span class="badge bg-success">release</span>
+						<a href="{{if $modulePages}}{{.Module}}/{{.Version}}/index.html{{else}}index.html#card-{{sanitizeID .Module}}{{end}}">{{.Module}} {{.Version}}</a>
+					{{else if eq .Kind "yank"}}
+						<span class="badge bg-danger">yanked</span>
+						<a href="index.html#card-{{sanitizeID .Module}}">{{.Module}} {{.Version}}</a>{{with .Detail}}: {{.}}{{end}}
+					{{else if eq .Kind "unyank"}}
+						<span class="badge bg-secondary">unyanked</span>
+						<a href="index.html#card-{{sanitizeID .Module}}">{{.Module}} {{.Version}}</a>
+					{{end}}
+				</td>
+			</tr>
+			{{end}}
+		</table>
+{{end}}
+`
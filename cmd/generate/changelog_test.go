@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// commitAll commits all changes in the git repository at dir, at the given
+// date.
+func commitAll(t *testing.T, dir, date string) {
+	t.Helper()
+	when, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := worktree.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+		t.Fatal(err)
+	}
+	signature := &object.Signature{Name: "test", Email: "test@example.com", When: when}
+	if _, err := worktree.Commit("change", &git.CommitOptions{Author: signature, Committer: signature}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitChangelog(t *testing.T) {
+	repoDir := t.TempDir()
+	if _, err := git.PlainInit(repoDir, false); err != nil {
+		t.Fatal(err)
+	}
+	modulesDir := filepath.Join(repoDir, "modules")
+	writeTestFile(t, filepath.Join(repoDir, "README.md"), "readme\n")
+	writeTestModule(t, modulesDir, "lib", map[string]string{
+		"1.0.0": `module(name = "lib", version = "1.0.0")`,
+	})
+	commitAll(t, repoDir, "2025-01-01T10:00:00Z")
+
+	writeTestModule(t, modulesDir, "lib", map[string]string{
+		"1.0.0": `module(name = "lib", version = "1.0.0")`,
+		"1.1.0": `module(name = "lib", version = "1.1.0")`,
+	})
+	commitAll(t, repoDir, "2025-02-01T10:00:00Z")
+
+	if err := yank(modulesDir, "lib", "1.0.0", "broken"); err != nil {
+		t.Fatal(err)
+	}
+	commitAll(t, repoDir, "2025-03-01T10:00:00Z")
+
+	if err := unyank(modulesDir, "lib", "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	commitAll(t, repoDir, "2025-04-01T10:00:00Z")
+
+	events, err := gitChangelog(modulesDir)
+	if err != nil {
+		t.Fatalf("gitChangelog failed: %v", err)
+	}
+	var got []string
+	for _, e := range events {
+		got = append(got, strings.Join([]string{e.Time.UTC().Format(time.DateOnly), e.Kind, e.Module, e.Version, e.Detail}, " "))
+		if len(e.Commit) != 40 {
+			t.Errorf("expected a commit hash, got %q", e.Commit)
+		}
+	}
+	want := []string{
+		"2025-04-01 unyank lib 1.0.0 ",
+		"2025-03-01 yank lib 1.0.0 broken",
+		"2025-02-01 version lib 1.1.0 ",
+		"2025-01-01 module lib  ",
+		"2025-01-01 version lib 1.0.0 ",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("gitChangelog() =\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	changelogPath := filepath.Join(t.TempDir(), "changelog.json")
+	content, err := marshalJSON(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, changelogPath, string(content))
+	loaded, err := loadChangelog(changelogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != len(events) || !loaded[0].Time.Equal(events[0].Time) {
+		t.Errorf("loadChangelog() = %v, want %v", loaded, events)
+	}
+
+	outputDir := t.TempDir()
+	if err := writeChangelogPage(testTemplates(t, ""), events, true, outputDir, SiteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	page, err := os.ReadFile(filepath.Join(outputDir, "changelog.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<a href="lib/1.1.0/index.html">lib 1.1.0</a>`,
+		`lib 1.0.0</a>: broken`,
+		`2025-01-01`,
+	} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("expected the changelog page to contain %q", want)
+		}
+	}
+}
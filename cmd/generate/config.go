@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+)
+
+// Config holds user settings for the generator, read from a JSON file.
+type Config struct {
+	// Maintainers are the default maintainers of newly added modules.
+	Maintainers []Maintainer `json:"maintainers"`
+	// Site brands the generated pages.
+	Site Branding `json:"site"`
+}
+
+// Branding holds what identifies the registry on the generated pages. Empty
+// fields take their values from defaultBranding.
+type Branding struct {
+	// Title names the registry in page titles and in the feed.
+	Title string `json:"title,omitempty"`
+	// Heading is the HTML of the heading of the index page.
+	Heading template.HTML `json:"heading,omitempty"`
+	// Intro is the HTML below the heading of the index page. If empty, the
+	// index page points to RepoURL for how to use the registry.
+	Intro template.HTML `json:"intro,omitempty"`
+	// RepoURL is the repository of the registry.
+	RepoURL string `json:"repo_url,omitempty"`
+	// BrowseURL is where the modules directory of the registry can be
+	// browsed. Pages link to BrowseURL/<module>/<version>.
+	BrowseURL string `json:"browse_url,omitempty"`
+	// SiteURL is where the generated pages are published.
+	SiteURL string `json:"site_url,omitempty"`
+	// RegistryURL is the URL that Bazel uses the registry at.
+	RegistryURL string `json:"registry_url,omitempty"`
+	// BadgeLabel is the label of the badges of the modules.
+	BadgeLabel string `json:"badge_label,omitempty"`
+	// Favicon is the path of the page icon, relative to the index page.
+	Favicon string `json:"favicon,omitempty"`
+	// Footer is the HTML of the footer of each page.
+	Footer template.HTML `json:"footer,omitempty"`
+	// AnalyticsID is the Google Analytics measurement ID. Analytics are off
+	// if empty.
+	AnalyticsID string `json:"analytics_id,omitempty"`
+}
+
+// defaultBranding is the branding of this registry.
+var defaultBranding = Branding{
+	Title: "Bazel Registry",
+	Heading: `<a href="https://www.hdlfactory.com">My</a> <a
+			href="https://bazel.build">Bazel</a> Registry`,
+	RepoURL:     "https://github.com/filmil/bazel-registry",
+	BrowseURL:   "https://github.com/filmil/bazel-registry/tree/main/modules",
+	SiteURL:     "https://hdlfactory.com/bazel-registry/",
+	RegistryURL: "https://raw.githubusercontent.com/filmil/bazel-registry/main",
+	BadgeLabel:  "filmil registry",
+	Favicon:     "hdlfactory.png",
+	Footer: `<p>&copy; 2025-present Filip Filmar. All rights reserved.</p>
+        <p><small>This page was generated by an automated coding assistant.</small></p>`,
+}
+
+// withDefaults fills the empty fields of b from defaultBranding.
+func (b Branding) withDefaults() Branding {
+	if b.Title == "" {
+		b.Title = defaultBranding.Title
+	}
+	if b.Heading == "" {
+		b.Heading = defaultBranding.Heading
+	}
+	if b.RepoURL == "" {
+		b.RepoURL = defaultBranding.RepoURL
+	}
+	if b.BrowseURL == "" {
+		b.BrowseURL = defaultBranding.BrowseURL
+	}
+	if b.SiteURL == "" {
+		b.SiteURL = defaultBranding.SiteURL
+	}
+	if b.RegistryURL == "" {
+		b.RegistryURL = defaultBranding.RegistryURL
+	}
+	if b.BadgeLabel == "" {
+		b.BadgeLabel = defaultBranding.BadgeLabel
+	}
+	if b.Favicon == "" {
+		b.Favicon = defaultBranding.Favicon
+	}
+	if b.Footer == "" {
+		b.Footer = defaultBranding.Footer
+	}
+	return b
+}
+
+// defaultConfigPath returns the location of the config file used when
+// --config is not given.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "bazel-registry", "config.json")
+}
+
+// loadConfig reads the config file at path. If path is empty, the default
+// config file is read if it exists.
+func loadConfig(path string) (Config, error) {
+	var config Config
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+		if path == "" {
+			return config, nil
+		}
+	}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) && !explicit {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return config, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return config, nil
+}
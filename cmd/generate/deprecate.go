@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+)
+
+func runDeprecate(args []string) error {
+	var modulesDir, module, message, replacement string
+	fs := flag.NewFlagSet("deprecate", flag.ExitOnError)
+	fs.StringVar(&modulesDir, "modules_dir", "modules", "The path to the modules directory.")
+	fs.StringVar(&module, "module", "", "The name of the module to deprecate.")
+	fs.StringVar(&message, "message", "", "The deprecation message. Shown to users of the module.")
+	fs.StringVar(&replacement, "replacement", "", "The module that replaces the deprecated one, if any.")
+	fs.Parse(args)
+	for name, value := range map[string]string{"module": module, "message": message} {
+		if value == "" {
+			return fmt.Errorf("flag --%s=... is required", name)
+		}
+	}
+	return deprecate(modulesDir, module, message, replacement)
+}
+
+// deprecate marks a whole module as deprecated, optionally pointing at the
+// module that replaces it.
+func deprecate(modulesDir, module, message, replacement string) error {
+	if replacement == module {
+		return fmt.Errorf("module %s can not replace itself", module)
+	}
+	if replacement != "" {
+		if _, err := loadModule(modulesDir, replacement); err != nil {
+			return fmt.Errorf("replacement module %s: %w", replacement, err)
+		}
+	}
+	metadataPath := filepath.Join(modulesDir, module, "metadata.json")
+	metadata, err := readJSONObject(metadataPath)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata for module %q: %w", module, err)
+	}
+	if err := metadata.Set("deprecated", message); err != nil {
+		return err
+	}
+	if replacement != "" {
+		if err := metadata.Set("replaced_by", replacement); err != nil {
+			return err
+		}
+	} else {
+		metadata.Delete("replaced_by")
+	}
+	return writeJSONObject(metadataPath, metadata)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeprecate(t *testing.T) {
+	modulesDir := t.TempDir()
+	writeTestModule(t, modulesDir, "bazel_rules_x", map[string]string{"1.0.0": `module(name = "bazel_rules_x", version = "1.0.0")`})
+	writeTestModule(t, modulesDir, "rules_x", map[string]string{"2.0.0": `module(name = "rules_x", version = "2.0.0")`})
+
+	if err := deprecate(modulesDir, "bazel_rules_x", "Renamed.", "rules_y"); err == nil {
+		t.Errorf("expected an error for a replacement that does not exist")
+	}
+	if err := deprecate(modulesDir, "bazel_rules_x", "Renamed.", "rules_x"); err != nil {
+		t.Fatalf("deprecate failed: %v", err)
+	}
+	modules, err := findModules(modulesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := modules[0].Metadata
+	if md.Deprecated != "Renamed." || md.ReplacedBy != "rules_x" {
+		t.Errorf("unexpected metadata: %+v", md)
+	}
+	metadata, _ := readJSONObject(filepath.Join(modulesDir, "bazel_rules_x", "metadata.json"))
+	if got := strings.Join(metadata.keys, ","); got != "homepage,maintainers,repository,versions,yanked_versions,deprecated,replaced_by" {
+		t.Errorf("unexpected metadata.json keys: %s", got)
+	}
+
+	if mermaid := buildMermaidWithLinks(modules, cardLink, false); strings.Contains(mermaid, "bazel_rules_x") {
+		t.Errorf("expected deprecated module to be excluded from the graph, got: %s", mermaid)
+	}
+
+	var buf bytes.Buffer
+	if err := generateHTML(modules, "", &dummyWriteCloser{Buffer: &buf}); err != nil {
+		t.Fatalf("generateHTML failed: %v", err)
+	}
+	output := buf.String()
+	if !strings.Contains(output, `module-card deprecated" id="card-bazel_rules_x"`) {
+		t.Errorf("expected the deprecated card to be marked")
+	}
+	if !strings.Contains(output, `Use <a href="#card-rules_x">rules_x</a> instead.`) {
+		t.Errorf("expected a link to the replacement module")
+	}
+}
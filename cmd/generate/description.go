@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// About is the sidecar file about.json of a module, which describes the
+// module without touching its metadata.json. The fields of metadata.json
+// take precedence.
+type About struct {
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// maxDescriptionLength is the length that descriptions taken from a README
+// are cut to.
+const maxDescriptionLength = 200
+
+var validTag = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// loadAbout merges the about.json of the module into its metadata, if the
+// file exists.
+func loadAbout(fsys fs.FS, name string, metadata *Metadata) error {
+	content, err := fs.ReadFile(fsys, path.Join(name, "about.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var about About
+	if err := json.Unmarshal(content, &about); err != nil {
+		return fmt.Errorf("failed to parse about.json: %w", err)
+	}
+	if metadata.Description == "" {
+		metadata.Description = about.Description
+	}
+	if len(metadata.Tags) == 0 {
+		metadata.Tags = about.Tags
+	}
+	return nil
+}
+
+// Category is the first tag of the module, or empty if it has none.
+func (m Module) Category() string {
+	if len(m.Metadata.Tags) == 0 {
+		return ""
+	}
+	return m.Metadata.Tags[0]
+}
+
+// describeFromREADME sets the description of the modules that have none to
+// the first paragraph of the README in the source archive of their latest
+// version, if the archive is in cacheDir.
+func describeFromREADME(modules []Module, cacheDir string) {
+	for i := range modules {
+		m := &modules[i]
+		if m.Metadata.Description != "" || len(m.Versions) == 0 {
+			continue
+		}
+		description, err := readmeDescription(cacheDir, m.Versions[0].Source)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			log.Printf("module %s: no description from the README: %v", m.Name, err)
+			continue
+		}
+		m.Metadata.Description = description
+	}
+}
+
+// readmeDescription returns the first paragraph of the README at the top of
+// the source archive, which must be in cacheDir.
+func readmeDescription(cacheDir string, source Source) (string, error) {
+	archive, err := findCachedArchive(cacheDir, source)
+	if err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(archive)
+	if err != nil {
+		return "", err
+	}
+	ext, err := archiveExtension(source)
+	if err != nil {
+		return "", err
+	}
+	files, err := archiveFiles(ext, content)
+	if err != nil {
+		return "", err
+	}
+	prefix := source.StripPrefix
+	if prefix == "" {
+		prefix = commonPrefix(files)
+	}
+	for _, name := range []string{"README.md", "README", "README.txt", "readme.md", "README.rst"} {
+		if readme, ok := files[path.Join(prefix, name)]; ok {
+			if description := firstParagraph(string(readme)); description != "" {
+				return description, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no README in %s: %w", source.URL, fs.ErrNotExist)
+}
+
+var (
+	markdownImage = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	markdownLink  = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	markdownTag   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// firstParagraph returns the first paragraph of prose in a README, as plain
+// text. Headings, badges, HTML, code blocks and lists are skipped.
+func firstParagraph(readme string) string {
+	var paragraph []string
+	inCode := false
+	for _, line := range splitLines(readme) {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		text := markdownImage.ReplaceAllString(trimmed, "")
+		text = markdownLink.ReplaceAllString(text, "$1")
+		text = markdownTag.ReplaceAllString(text, "")
+		text = strings.NewReplacer("**", "", "__", "", "`", "").Replace(text)
+		text = strings.TrimSpace(text)
+		switch {
+		case text != "" && strings.Trim(text, "=-") == "":
+			// The underline of a heading.
+			paragraph = nil
+		case text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "- ") ||
+			strings.HasPrefix(text, "* ") || strings.HasPrefix(text, "|"):
+			if len(paragraph) > 0 {
+				return shorten(strings.Join(paragraph, " "))
+			}
+		default:
+			paragraph = append(paragraph, text)
+		}
+	}
+	return shorten(strings.Join(paragraph, " "))
+}
+
+// shorten cuts s to maxDescriptionLength at a word boundary.
+func shorten(s string) string {
+	if len(s) <= maxDescriptionLength {
+		return s
+	}
+	s = s[:maxDescriptionLength]
+	if i := strings.LastIndex(s, " "); i > 0 {
+		s = s[:i]
+	}
+	return strings.TrimRight(s, ",.;:") + "…"
+}
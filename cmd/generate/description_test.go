@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFirstParagraph(t *testing.T) {
+	for _, test := range []struct {
+		name, readme, want string
+	}{
+		{
+			name: "markdown",
+			readme: `# rules_x [![CI](https://ci/badge.svg)](https://ci)
+
+<img src="logo.png">
+
+` + "```" + `
+bazel_dep(name = "rules_x")
+` + "```" + `
+
+Bazel rules for the **X** compiler,
+see [the docs](https://x.dev) and ` + "`x_binary`" + `.
+
+More text.
+`,
+			want: "Bazel rules for the X compiler, see the docs and x_binary.",
+		},
+		{
+			name:   "underlined heading",
+			readme: "rules_y\n=======\n\nRules for Y.\n",
+			want:   "Rules for Y.",
+		},
+		{
+			name:   "only a list",
+			readme: "# z\n\n- one\n- two\n",
+			want:   "",
+		},
+		{
+			name:   "long",
+			readme: strings.Repeat("word ", 100),
+			want:   strings.TrimSpace(strings.Repeat("word ", 40)) + "…",
+		},
+	} {
+		if got := firstParagraph(test.readme); got != test.want {
+			t.Errorf("%s: firstParagraph() = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAbout(t *testing.T) {
+	modulesDir := t.TempDir()
+	writeTestModule(t, modulesDir, "lib", map[string]string{"1.0.0": `module(name = "lib", version = "1.0.0")`})
+	writeTestFile(t, filepath.Join(modulesDir, "lib", "about.json"), `{"description": "A library.", "tags": ["hdl", "fpga"]}`)
+	m, err := loadModule(modulesDir, "lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Metadata.Description != "A library." || !reflect.DeepEqual(m.Metadata.Tags, []string{"hdl", "fpga"}) {
+		t.Errorf("expected the description and tags from about.json, got %+v", m.Metadata)
+	}
+	if m.Category() != "hdl" {
+		t.Errorf("Category() = %q, want hdl", m.Category())
+	}
+	if err := validateModule(m); err != nil {
+		t.Errorf("expected the module to be valid, got: %v", err)
+	}
+
+	// metadata.json takes precedence.
+	metadataPath := filepath.Join(modulesDir, "lib", "metadata.json")
+	content, _ := os.ReadFile(metadataPath)
+	writeTestFile(t, metadataPath, strings.Replace(string(content), "{", `{"tags": ["Bad Tag"],`, 1))
+	m, err = loadModule(modulesDir, "lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Metadata.Description != "A library." || !reflect.DeepEqual(m.Metadata.Tags, []string{"Bad Tag"}) {
+		t.Errorf("expected the tags from metadata.json, got %+v", m.Metadata)
+	}
+	if err := validateModule(m); err == nil || !strings.Contains(err.Error(), `invalid tag "Bad Tag"`) {
+		t.Errorf("expected an invalid tag error, got: %v", err)
+	}
+
+	writeTestFile(t, filepath.Join(modulesDir, "lib", "about.json"), `{`)
+	if _, err := loadModule(modulesDir, "lib"); err == nil {
+		t.Errorf("expected an error for a malformed about.json")
+	}
+}
+
+func TestDescribeFromREADME(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	archive := filepath.Join(cacheDir, "lib-1.0.0.tar.gz")
+	writeTestArchive(t, archive, map[string]string{
+		"lib-1.0.0/README.md":    "# lib\n\nThe lib library.\n",
+		"lib-1.0.0/MODULE.bazel": `module(name = "lib")`,
+	})
+	content, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modules := []Module{
+		{Name: "lib", Versions: []Version{{Name: "1.0.0", Source: Source{
+			URL:       "https://example.com/lib-1.0.0.tar.gz",
+			Integrity: integrity(content),
+		}}}},
+		{Name: "described", Metadata: Metadata{Description: "Kept."}, Versions: []Version{{Name: "1.0.0"}}},
+		{Name: "uncached", Versions: []Version{{Name: "1.0.0", Source: Source{
+			URL:       "https://example.com/uncached-1.0.0.tar.gz",
+			Integrity: integrity(nil),
+		}}}},
+	}
+	describeFromREADME(modules, cacheDir)
+	for i, want := range []string{"The lib library.", "Kept.", ""} {
+		if got := modules[i].Metadata.Description; got != want {
+			t.Errorf("module %s: description = %q, want %q", modules[i].Name, got, want)
+		}
+	}
+}
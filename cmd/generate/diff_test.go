@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	oldText := "module(\n    name = \"m\",\n    version = \"0.0.0\",\n)\n\nbazel_dep(name = \"a\", version = \"1\")\n"
+	newText := "module(\n    name = \"m\",\n    version = \"1.2.3\",\n)\n\nbazel_dep(name = \"a\", version = \"1\")\n"
+	want := `===================================================================
+--- a/MODULE.bazel
++++ b/MODULE.bazel
+@@ -1,6 +1,6 @@
+ module(
+     name = "m",
+-    version = "0.0.0",
++    version = "1.2.3",
+ )
+ 
+ bazel_dep(name = "a", version = "1")
+`
+	if got := unifiedDiff("a/MODULE.bazel", "b/MODULE.bazel", oldText, newText); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if got := unifiedDiff("a", "b", oldText, oldText); got != "" {
+		t.Errorf("expected no diff for equal texts, got:\n%s", got)
+	}
+}
+
+func TestUnifiedDiff_Insertion(t *testing.T) {
+	got := unifiedDiff("a/f", "b/f", "", "x\n")
+	want := "===================================================================\n--- a/f\n+++ b/f\n@@ -0,0 +1,1 @@\n+x\n"
+	if got != want {
+		t.Errorf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
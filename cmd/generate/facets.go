@@ -0,0 +1,164 @@
+package main
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// The statuses of a module.
+const (
+	statusActive     = "active"
+	statusDeprecated = "deprecated"
+	statusYanked     = "yanked"
+)
+
+// Status is statusDeprecated for deprecated modules, statusYanked for
+// modules whose latest version is yanked, and statusActive otherwise.
+func (m Module) Status() string {
+	if m.Metadata.Deprecated != "" {
+		return statusDeprecated
+	}
+	if len(m.Versions) > 0 {
+		if _, ok := m.Metadata.YankedVersions[m.Versions[0].Name]; ok {
+			return statusYanked
+		}
+	}
+	return statusActive
+}
+
+// SourceHost is the host that the latest version of the module is
+// downloaded from, or empty if unknown.
+func (m Module) SourceHost() string {
+	if len(m.Versions) == 0 {
+		return ""
+	}
+	u, err := url.Parse(m.Versions[0].Source.URL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// Attested reports whether the latest version of the module has provenance
+// attestations.
+func (m Module) Attested() bool {
+	return len(m.Versions) > 0 && m.Versions[0].Attestations != nil
+}
+
+// maintainerKey identifies a maintainer in the filters of the index page:
+// by GitHub handle, or else by name or email.
+func maintainerKey(maintainer Maintainer) string {
+	switch {
+	case maintainer.GitHub != "":
+		return maintainer.GitHub
+	case maintainer.Name != "":
+		return maintainer.Name
+	default:
+		return maintainer.Email
+	}
+}
+
+// facetValue is a value that modules can be filtered by, with the number of
+// modules that have it.
+type facetValue struct {
+	Value string
+	Count int
+}
+
+// Facets are the values that the modules on the index page can be
+// filtered by.
+type Facets struct {
+	Tags        []facetValue
+	Maintainers []facetValue
+	Hosts       []facetValue
+	Statuses    []facetValue
+	// Deps are the dependencies of the latest versions.
+	Deps []facetValue
+	// Attested is the number of modules with attestations.
+	Attested int
+}
+
+// buildFacets collects the values of the facets of modules, each sorted by
+// value.
+func buildFacets(modules []Module) Facets {
+	tags := make(map[string]int)
+	maintainers := make(map[string]int)
+	hosts := make(map[string]int)
+	statuses := make(map[string]int)
+	deps := make(map[string]int)
+	var facets Facets
+	for _, m := range modules {
+		for _, tag := range m.Metadata.Tags {
+			tags[tag]++
+		}
+		seen := make(map[string]bool)
+		for _, maintainer := range m.Metadata.Maintainers {
+			if key := maintainerKey(maintainer); key != "" && !seen[key] {
+				seen[key] = true
+				maintainers[key]++
+			}
+		}
+		if host := m.SourceHost(); host != "" {
+			hosts[host]++
+		}
+		statuses[m.Status()]++
+		if len(m.Versions) > 0 {
+			seen := make(map[string]bool)
+			for _, dep := range m.Versions[0].Dependencies {
+				if !seen[dep.Name] {
+					seen[dep.Name] = true
+					deps[dep.Name]++
+				}
+			}
+		}
+		if m.Attested() {
+			facets.Attested++
+		}
+	}
+	facets.Tags = facetValues(tags)
+	facets.Maintainers = facetValues(maintainers)
+	facets.Hosts = facetValues(hosts)
+	facets.Statuses = facetValues(statuses)
+	facets.Deps = facetValues(deps)
+	return facets
+}
+
+// SearchExample is an example query for the search box of the index page,
+// with the most common tag, dependency and maintainer of the registry.
+func (f Facets) SearchExample() string {
+	terms := []string{}
+	if tag := mostCommonTerm(f.Tags); tag != "" {
+		terms = append(terms, tag)
+	}
+	if dep := mostCommonTerm(f.Deps); dep != "" {
+		terms = append(terms, "dep:"+dep)
+	}
+	if maintainer := mostCommonTerm(f.Maintainers); maintainer != "" {
+		terms = append(terms, "maintainer:"+maintainer)
+	}
+	return strings.Join(terms, " ")
+}
+
+// mostCommonTerm returns the value that most modules have among the values
+// that can be searched for as a single term, or "" if there is none.
+func mostCommonTerm(values []facetValue) string {
+	var best facetValue
+	for _, v := range values {
+		if v.Count > best.Count && !strings.ContainsAny(v.Value, " \t:") {
+			best = v
+		}
+	}
+	return best.Value
+}
+
+func facetValues(counts map[string]int) []facetValue {
+	var values []facetValue
+	for value, count := range counts {
+		values = append(values, facetValue{value, count})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Value < values[j].Value
+	})
+	return values
+}
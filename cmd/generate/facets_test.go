@@ -0,0 +1,84 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModuleFacets(t *testing.T) {
+	m := Module{
+		Name: "a",
+		Metadata: Metadata{
+			YankedVersions: map[string]string{"1.1.0": "broken"},
+		},
+		Versions: []Version{
+			{Name: "1.1.0", Source: Source{URL: "https://github.com/o/a/archive/v1.1.0.tar.gz"}},
+			{Name: "1.0.0", Attestations: &Attestations{}},
+		},
+	}
+	if got := m.Status(); got != statusYanked {
+		t.Errorf("Status() = %q, want %q", got, statusYanked)
+	}
+	if got := m.SourceHost(); got != "github.com" {
+		t.Errorf("SourceHost() = %q, want github.com", got)
+	}
+	if m.Attested() {
+		t.Errorf("expected only the latest version to count for Attested()")
+	}
+
+	m.Metadata.Deprecated = "Use b."
+	if got := m.Status(); got != statusDeprecated {
+		t.Errorf("Status() = %q, want %q", got, statusDeprecated)
+	}
+	if got := (Module{Name: "empty"}).Status(); got != statusActive {
+		t.Errorf("Status() = %q, want %q", got, statusActive)
+	}
+}
+
+func TestBuildFacets(t *testing.T) {
+	modules := []Module{
+		{
+			Name: "a",
+			Metadata: Metadata{
+				Tags:        []string{"hdl", "fpga"},
+				Maintainers: []Maintainer{{Name: "Jane Doe", GitHub: "jdoe"}, {Email: "x@example.com"}},
+			},
+			Versions: []Version{{
+				Name:         "1.0.0",
+				Source:       Source{URL: "https://github.com/o/a/archive/v1.0.0.tar.gz"},
+				Attestations: &Attestations{},
+				Dependencies: []Dependency{{Name: "rules_cc"}, {Name: "rules_cc", DevDependency: true}, {Name: "rules_python"}},
+			}},
+		},
+		{
+			Name: "b",
+			Metadata: Metadata{
+				Tags:        []string{"fpga"},
+				Maintainers: []Maintainer{{GitHub: "jdoe"}, {Name: "jdoe", GitHub: "jdoe"}},
+				Deprecated:  "Use a.",
+			},
+			Versions: []Version{
+				{Name: "1.0.0", Source: Source{URL: "https://gitlab.com/o/b.tar.gz"}, Dependencies: []Dependency{{Name: "rules_cc"}}},
+				{Name: "0.9.0", Dependencies: []Dependency{{Name: "rules_python"}}},
+			},
+		},
+		{Name: "c"},
+	}
+	want := Facets{
+		Tags:        []facetValue{{"fpga", 2}, {"hdl", 1}},
+		Maintainers: []facetValue{{"jdoe", 2}, {"x@example.com", 1}},
+		Hosts:       []facetValue{{"github.com", 1}, {"gitlab.com", 1}},
+		Statuses:    []facetValue{{statusActive, 2}, {statusDeprecated, 1}},
+		Deps:        []facetValue{{"rules_cc", 2}, {"rules_python", 1}},
+		Attested:    1,
+	}
+	if got := buildFacets(modules); !reflect.DeepEqual(got, want) {
+		t.Errorf("buildFacets() = %+v, want %+v", got, want)
+	}
+	if got := want.SearchExample(); got != "fpga dep:rules_cc maintainer:jdoe" {
+		t.Errorf("SearchExample() = %q", got)
+	}
+	if got := (Facets{Maintainers: []facetValue{{"Jane Doe", 3}}}).SearchExample(); got != "" {
+		t.Errorf("SearchExample() = %q, want no terms", got)
+	}
+}
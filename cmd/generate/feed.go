@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// atomFeed is an Atom feed, as described in RFC 4287.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title   string   `xml:"title"`
+	ID      string   `xml:"id"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Summary string   `xml:"summary"`
+}
+
+// buildFeed builds an Atom feed of the module versions with a known
+// publication time, newest first. Links are relative to the site URL, which
+// is where the index page is published.
+func buildFeed(modules []Module, branding Branding) atomFeed {
+	branding = branding.withDefaults()
+	siteURL := branding.SiteURL
+	if !strings.HasSuffix(siteURL, "/") {
+		siteURL += "/"
+	}
+	type release struct {
+		module  Module
+		version Version
+		time    time.Time
+	}
+	var releases []release
+	for _, m := range modules {
+		for _, v := range m.Versions {
+			if !v.Published.IsZero() {
+				releases = append(releases, release{m, v, v.Published})
+			}
+		}
+	}
+	sort.SliceStable(releases, func(i, j int) bool {
+		return releases[i].time.After(releases[j].time)
+	})
+
+	feed := atomFeed{
+		Title: branding.Title + " releases",
+		ID:    siteURL + "feed.xml",
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: siteURL + "feed.xml"},
+			{Rel: "alternate", Type: "text/html", Href: siteURL + "index.html"},
+		},
+		Author: atomAuthor{Name: branding.Title},
+	}
+	if len(releases) > 0 {
+		feed.Updated = releases[0].time.UTC().Format(time.RFC3339)
+	} else {
+		feed.Updated = time.Unix(0, 0).UTC().Format(time.RFC3339)
+	}
+	for _, r := range releases {
+		title := r.module.Name + " " + r.version.Name
+		if _, ok := r.module.Metadata.YankedVersions[r.version.Name]; ok {
+			title += " (yanked)"
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   title,
+			ID:      siteURL + "index.html#card-" + sanitizeID(r.module.Name) + "/" + r.version.Name,
+			Updated: r.time.UTC().Format(time.RFC3339),
+			Link:    atomLink{Rel: "alternate", Type: "text/html", Href: siteURL + "index.html#card-" + sanitizeID(r.module.Name)},
+			Summary: dependencySummary(r.version.Dependencies),
+		})
+	}
+	return feed
+}
+
+// dependencySummary describes the dependencies of a version in one line.
+func dependencySummary(deps []Dependency) string {
+	if len(deps) == 0 {
+		return "No dependencies."
+	}
+	var parts []string
+	for _, dep := range deps {
+		part := dep.Name + " " + dep.Version
+		if dep.DevDependency {
+			part += " (dev)"
+		}
+		parts = append(parts, part)
+	}
+	return "Depends on " + strings.Join(parts, ", ") + "."
+}
+
+func writeFeed(feed atomFeed, w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(feed); err != nil {
+		return fmt.Errorf("failed to write feed: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// writeFeedFile writes the feed to feed.xml in outputDir.
+func writeFeedFile(feed atomFeed, outputDir string) error {
+	f, err := os.Create(filepath.Join(outputDir, "feed.xml"))
+	if err != nil {
+		return err
+	}
+	if err := writeFeed(feed, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
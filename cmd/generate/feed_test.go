@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildFeed(t *testing.T) {
+	modules := []Module{
+		{
+			Name: "lib",
+			Metadata: Metadata{
+				YankedVersions: map[string]string{"1.0.0": "broken"},
+			},
+			Versions: []Version{
+				{Name: "1.1.0", Dependencies: []Dependency{
+					{Name: "rules_cc", Version: "0.1"},
+					{Name: "rules_testing", Version: "0.2", DevDependency: true},
+				}},
+				{Name: "1.0.0"},
+				{Name: "0.9.0"},
+			},
+		},
+	}
+	events := []ChangeEvent{
+		{Time: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), Kind: eventVersion, Module: "lib", Version: "1.1.0"},
+		{Time: time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC), Kind: eventYank, Module: "lib", Version: "1.0.0"},
+		{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Kind: eventVersion, Module: "lib", Version: "1.0.0"},
+	}
+	setPublished(modules, events)
+	feed := buildFeed(modules, Branding{SiteURL: "https://example.com/registry"})
+
+	if feed.Updated != "2025-02-01T00:00:00Z" {
+		t.Errorf("Updated = %q", feed.Updated)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", feed.Entries)
+	}
+	first := feed.Entries[0]
+	if first.Title != "lib 1.1.0" || first.Link.Href != "https://example.com/registry/index.html#card-lib" {
+		t.Errorf("first entry = %+v", first)
+	}
+	if want := "Depends on rules_cc 0.1, rules_testing 0.2 (dev)."; first.Summary != want {
+		t.Errorf("Summary = %q, want %q", first.Summary, want)
+	}
+	if feed.Entries[1].Title != "lib 1.0.0 (yanked)" {
+		t.Errorf("second entry = %+v", feed.Entries[1])
+	}
+
+	var sb strings.Builder
+	if err := writeFeed(feed, &sb); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sb.String(), `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<feed xmlns="http://www.w3.org/2005/Atom">`) {
+		t.Errorf("unexpected feed:\n%s", sb.String())
+	}
+	var parsed atomFeed
+	if err := xml.Unmarshal([]byte(sb.String()), &parsed); err != nil {
+		t.Fatalf("failed to parse the feed: %v", err)
+	}
+	if len(parsed.Entries) != 2 {
+		t.Errorf("parsed %d entries, want 2", len(parsed.Entries))
+	}
+}
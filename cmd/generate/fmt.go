@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+var (
+	metadataKeyOrder   = []string{"homepage", "description", "tags", "maintainers", "repository", "versions", "yanked_versions", "deprecated", "replaced_by", "published_at"}
+	maintainerKeyOrder = []string{"name", "email", "github", "github_user_id"}
+	sourceKeyOrder     = []string{"type", "integrity", "strip_prefix", "url", "mirror_urls", "archive_type", "docs_url", "patches", "patch_strip", "overlay"}
+)
+
+func runFmt(args []string) error {
+	var (
+		modulesDir string
+		check      bool
+	)
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	fs.StringVar(&modulesDir, "modules_dir", "modules", "The path to the modules directory.")
+	fs.BoolVar(&check, "check", false, "Only report files that are not canonical, and fail if there are any.")
+	fs.Parse(args)
+
+	changed, err := formatRegistry(modulesDir, check)
+	if err != nil {
+		return err
+	}
+	for _, path := range changed {
+		if check {
+			log.Printf("not canonical: %s", path)
+		} else {
+			log.Printf("formatted: %s", path)
+		}
+	}
+	if check && len(changed) > 0 {
+		return fmt.Errorf("%d files are not canonical, run the fmt subcommand to fix", len(changed))
+	}
+	return nil
+}
+
+// formatRegistry rewrites the metadata.json files in modulesDir in canonical
+// form, and returns the paths of the files that were not canonical. If check
+// is set, no files are written. The files of published versions, such as
+// source.json, are never rewritten: they are immutable, and their hashes are
+// pinned in MODULE.bazel.lock files.
+func formatRegistry(modulesDir string, check bool) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(modulesDir, "*", "metadata.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		canonical, err := canonicalMetadata(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if bytes.Equal(content, canonical) {
+			continue
+		}
+		changed = append(changed, path)
+		if check {
+			continue
+		}
+		if err := os.WriteFile(path, canonical, 0644); err != nil {
+			return nil, err
+		}
+	}
+	return changed, nil
+}
+
+// canonicalMetadata returns metadata.json content in canonical form: keys
+// in a stable order, versions sorted by version order and without
+// duplicates, and maintainer, yanked version and publication date keys in a
+// stable order.
+func canonicalMetadata(content []byte) ([]byte, error) {
+	var o jsonObject
+	if err := json.Unmarshal(content, &o); err != nil {
+		return nil, err
+	}
+	o.Reorder(metadataKeyOrder)
+
+	if o.Has("maintainers") {
+		var maintainers []jsonObject
+		if err := o.Get("maintainers", &maintainers); err != nil {
+			return nil, err
+		}
+		for i := range maintainers {
+			maintainers[i].Reorder(maintainerKeyOrder)
+		}
+		if err := o.Set("maintainers", maintainers); err != nil {
+			return nil, err
+		}
+	}
+	if o.Has("versions") {
+		var versions []string
+		if err := o.Get("versions", &versions); err != nil {
+			return nil, err
+		}
+		sortVersions(versions)
+		unique := []string{}
+		for i, v := range versions {
+			if i == 0 || v != versions[i-1] {
+				unique = append(unique, v)
+			}
+		}
+		if err := o.Set("versions", unique); err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range []string{"yanked_versions", "published_at"} {
+		if err := sortObjectKeys(&o, key); err != nil {
+			return nil, err
+		}
+	}
+	return marshalJSON(&o)
+}
+
+// sortObjectKeys sorts the keys of the object stored at key, if any.
+func sortObjectKeys(o *jsonObject, key string) error {
+	if !o.Has(key) || string(o.values[key]) == "null" {
+		return nil
+	}
+	var inner jsonObject
+	if err := o.Get(key, &inner); err != nil {
+		return err
+	}
+	inner.SortKeys()
+	return o.Set(key, &inner)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCanonicalMetadata(t *testing.T) {
+	in := `{"versions": ["0.10.0", "0.9.0", "0.9.0"], "homepage": "https://example.com/a&b",
+  "yanked_versions": {"0.9.0": "b", "0.10.0": "a"},
+  "x_custom": 1,
+  "published_at": {"0.9.0": "2025-01-01", "0.10.0": "2025-02-01"},
+  "maintainers": [{"github_user_id": 1, "github": "jdoe", "name": "Jane"}]}`
+	want := `{
+    "homepage": "https://example.com/a&b",
+    "maintainers": [
+        {
+            "name": "Jane",
+            "github": "jdoe",
+            "github_user_id": 1
+        }
+    ],
+    "versions": [
+        "0.9.0",
+        "0.10.0"
+    ],
+    "yanked_versions": {
+        "0.10.0": "a",
+        "0.9.0": "b"
+    },
+    "published_at": {
+        "0.10.0": "2025-02-01",
+        "0.9.0": "2025-01-01"
+    },
+    "x_custom": 1
+}
+`
+	got, err := canonicalMetadata([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatRegistry(t *testing.T) {
+	modulesDir := t.TempDir()
+	writeTestModule(t, modulesDir, "mod", map[string]string{"1.0.0": `module(name = "mod", version = "1.0.0")`})
+	metadataPath := filepath.Join(modulesDir, "mod", "metadata.json")
+	writeTestFile(t, metadataPath, `{"versions": ["1.0.0"], "homepage": "h"}`)
+	// Published files are immutable, so source.json is left alone.
+	sourcePath := filepath.Join(modulesDir, "mod", "1.0.0", "source.json")
+	writeTestFile(t, sourcePath, `{"url": "u", "integrity": "i"}`)
+
+	changed, err := formatRegistry(modulesDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 1 || changed[0] != metadataPath {
+		t.Errorf("check reported %v, want only %s", changed, metadataPath)
+	}
+	if content, _ := os.ReadFile(metadataPath); string(content) != `{"versions": ["1.0.0"], "homepage": "h"}` {
+		t.Errorf("check modified %s", metadataPath)
+	}
+
+	if _, err := formatRegistry(modulesDir, false); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := formatRegistry(modulesDir, true); err != nil || len(changed) != 0 {
+		t.Errorf("expected a canonical registry after fmt, got: %v, %v", changed, err)
+	}
+	if content, _ := os.ReadFile(sourcePath); string(content) != `{"url": "u", "integrity": "i"}` {
+		t.Errorf("fmt modified %s", sourcePath)
+	}
+}
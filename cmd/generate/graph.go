@@ -0,0 +1,398 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+)
+
+// The dependency graph is laid out in layers, top to bottom, in the manner
+// of Sugiyama et al.: cycles are broken, nodes are assigned to layers by
+// their longest path from a root, edges that span several layers get
+// virtual nodes, and the nodes of each layer are ordered to reduce edge
+// crossings.
+
+// Sizes in the graph, in SVG user units. Labels are in a monospace font, so
+// that their width is known without a font renderer.
+const (
+	graphCharWidth  = 7.3
+	graphLineHeight = 16
+	graphPadding    = 8
+	graphNodeGap    = 24
+	graphLayerGap   = 56
+	graphMargin     = 16
+	// graphEdgeWidth is the width taken up in a layer by an edge that passes
+	// through it.
+	graphEdgeWidth = 8
+	// graphSweeps is the number of crossing reduction passes.
+	graphSweeps = 12
+)
+
+// graphNode is a node of the dependency graph.
+type graphNode struct {
+	ID    string
+	Label []string
+	// Link is where a click on the node goes. Empty for no link.
+	Link string
+	// Class is "leaf" for modules without dependencies in the registry,
+	// "deprecated" for deprecated modules, "inverted" for the node of the
+	// modules outside the registry, or empty.
+	Class string
+
+	// Set by layout. X and Y are the center of the node.
+	X, Y, Width, Height float64
+}
+
+type graphPoint struct {
+	X, Y float64
+}
+
+// graphEdge goes from a module to its dependency.
+type graphEdge struct {
+	From, To int
+	// Points is the route of the edge, set by layout.
+	Points []graphPoint
+}
+
+type depGraph struct {
+	Nodes         []graphNode
+	Edges         []graphEdge
+	Width, Height float64
+}
+
+// newDepGraph builds the dependency graph of the latest versions of modules,
+// with clicks on a module going to link(module name). Deprecated modules are
+// only in the graph if includeDeprecated is set, or if other modules in the
+// graph depend on them. The graph is not laid out.
+func newDepGraph(modules []Module, link func(name string) string, includeDeprecated bool) *depGraph {
+	g := &depGraph{}
+	registry := make(map[string]Module)
+	for _, m := range modules {
+		if len(m.Versions) > 0 {
+			registry[m.Name] = m
+		}
+	}
+	index := make(map[string]int) // module name -> node
+	addNode := func(m Module) int {
+		if i, ok := index[m.Name]; ok {
+			return i
+		}
+		index[m.Name] = len(g.Nodes)
+		node := graphNode{
+			ID:    sanitizeID(m.Name),
+			Label: []string{m.Name, m.Versions[0].Name},
+			Link:  link(m.Name),
+		}
+		if m.Metadata.Deprecated != "" {
+			node.Class = "deprecated"
+		}
+		g.Nodes = append(g.Nodes, node)
+		return index[m.Name]
+	}
+	var shown []Module
+	for _, m := range modules {
+		if len(m.Versions) > 0 && (includeDeprecated || m.Metadata.Deprecated == "") {
+			shown = append(shown, m)
+			addNode(m)
+		}
+	}
+
+	externalModules := make(map[string]string) // name -> version
+	edges := make(map[[2]int]bool)
+	external := -1
+	for _, m := range shown {
+		from := index[m.Name]
+		hasInternalDeps := false
+		for _, dep := range m.Versions[0].Dependencies {
+			var to int
+			if d, ok := registry[dep.Name]; ok {
+				to = addNode(d)
+				hasInternalDeps = true
+			} else {
+				externalModules[dep.Name] = dep.Version
+				if external < 0 {
+					external = len(g.Nodes)
+					g.Nodes = append(g.Nodes, graphNode{ID: "ExternalModules", Class: "inverted"})
+				}
+				to = external
+			}
+			if !edges[[2]int{from, to}] {
+				edges[[2]int{from, to}] = true
+				g.Edges = append(g.Edges, graphEdge{From: from, To: to})
+			}
+		}
+		if !hasInternalDeps && g.Nodes[from].Class == "" {
+			g.Nodes[from].Class = "leaf"
+		}
+	}
+	if external >= 0 {
+		var names []string
+		for name := range externalModules {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			g.Nodes[external].Label = append(g.Nodes[external].Label, fmt.Sprintf("%s (%s)", name, externalModules[name]))
+		}
+	}
+	return g
+}
+
+// buildGraph builds the dependency graph, see newDepGraph, and lays it out.
+func buildGraph(modules []Module, link func(name string) string, includeDeprecated bool) *depGraph {
+	g := newDepGraph(modules, link, includeDeprecated)
+	g.layout()
+	return g
+}
+
+// Mermaid returns the graph as a Mermaid flowchart.
+func (g *depGraph) Mermaid() string {
+	var sb strings.Builder
+	sb.WriteString(`---
+config:
+  layout: elk
+  elk:
+    mergeEdges: true
+---
+`)
+	sb.WriteString("flowchart TB\n")
+	escape := func(s string) string {
+		return strings.ReplaceAll(s, "\"", "\\\"")
+	}
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&sb, "    %s[\"%s\"]\n", node.ID, escape(strings.Join(node.Label, "\n")))
+	}
+	for _, e := range g.Edges {
+		// Use "jump" label on edges for navigation
+		fmt.Fprintf(&sb, "    %s -- \"jump\" --> %s\n", g.Nodes[e.From].ID, g.Nodes[e.To].ID)
+	}
+	for _, node := range g.Nodes {
+		if node.Class != "" {
+			fmt.Fprintf(&sb, "    class %s %s\n", node.ID, node.Class)
+		}
+	}
+	for _, node := range g.Nodes {
+		if node.Link != "" {
+			fmt.Fprintf(&sb, "    click %s \"%s\"\n", node.ID, escape(node.Link))
+		}
+	}
+	sb.WriteString("    classDef inverted fill:#333,color:#fff\n")
+	sb.WriteString("    classDef leaf fill:#28a745,color:#fff\n")
+	sb.WriteString("    classDef deprecated fill:#6c757d,color:#fff,stroke-dasharray:5 5\n")
+	return sb.String()
+}
+
+// layout sets the positions of the nodes and the routes of the edges.
+func (g *depGraph) layout() {
+	n := len(g.Nodes)
+	out := make([][]int, n)
+	for _, e := range g.Edges {
+		out[e.From] = append(out[e.From], e.To)
+	}
+
+	// Break cycles by ignoring the edges back to a node on the DFS stack.
+	// The reverse of the DFS finishing order is then a topological order.
+	const (
+		unvisited = iota
+		onStack
+		done
+	)
+	state := make([]int, n)
+	acyclic := make([][]int, n)
+	var finished []int
+	var visit func(u int)
+	visit = func(u int) {
+		state[u] = onStack
+		for _, v := range out[u] {
+			if state[v] == onStack {
+				continue
+			}
+			acyclic[u] = append(acyclic[u], v)
+			if state[v] == unvisited {
+				visit(v)
+			}
+		}
+		state[u] = done
+		finished = append(finished, u)
+	}
+	for u := 0; u < n; u++ {
+		if state[u] == unvisited {
+			visit(u)
+		}
+	}
+	layerOf := make([]int, n)
+	for i := len(finished) - 1; i >= 0; i-- {
+		u := finished[i]
+		for _, v := range acyclic[u] {
+			layerOf[v] = max(layerOf[v], layerOf[u]+1)
+		}
+	}
+
+	// Vertices are the nodes, followed by the virtual nodes of the edges
+	// that span more than one layer.
+	widths := make([]float64, n)
+	heights := make([]float64, n)
+	for i, node := range g.Nodes {
+		longest := 0
+		for _, line := range node.Label {
+			longest = max(longest, len([]rune(line)))
+		}
+		widths[i] = float64(longest)*graphCharWidth + 2*graphPadding
+		heights[i] = float64(len(node.Label))*graphLineHeight + 2*graphPadding
+	}
+	chains := make([][]int, len(g.Edges))
+	for i, e := range g.Edges {
+		chain := []int{e.From}
+		for l := layerOf[e.From] + 1; l < layerOf[e.To]; l++ {
+			chain = append(chain, len(layerOf))
+			layerOf = append(layerOf, l)
+			widths = append(widths, graphEdgeWidth)
+			heights = append(heights, 0)
+		}
+		chains[i] = append(chain, e.To)
+	}
+	up := make([][]int, len(layerOf))
+	down := make([][]int, len(layerOf))
+	for _, chain := range chains {
+		for i := 1; i < len(chain); i++ {
+			u, v := chain[i-1], chain[i]
+			if layerOf[v] == layerOf[u]+1 {
+				down[u] = append(down[u], v)
+				up[v] = append(up[v], u)
+			}
+		}
+	}
+
+	var layers [][]int
+	for v, l := range layerOf {
+		for len(layers) <= l {
+			layers = append(layers, nil)
+		}
+		layers[l] = append(layers[l], v)
+	}
+
+	// Order each layer by the mean position of the neighbors in the layer
+	// above, then in the layer below, and so on.
+	pos := make([]float64, len(layerOf))
+	setPositions := func(layer []int) {
+		for i, v := range layer {
+			pos[v] = float64(i)
+		}
+	}
+	for _, layer := range layers {
+		setPositions(layer)
+	}
+	barycenter := make([]float64, len(layerOf))
+	reorder := func(layer []int, neighbors [][]int) {
+		for _, v := range layer {
+			barycenter[v] = pos[v]
+			if len(neighbors[v]) > 0 {
+				sum := 0.0
+				for _, u := range neighbors[v] {
+					sum += pos[u]
+				}
+				barycenter[v] = sum / float64(len(neighbors[v]))
+			}
+		}
+		sort.SliceStable(layer, func(i, j int) bool {
+			return barycenter[layer[i]] < barycenter[layer[j]]
+		})
+		setPositions(layer)
+	}
+	for i := 0; i < graphSweeps; i++ {
+		for l := 1; l < len(layers); l++ {
+			reorder(layers[l], up)
+		}
+		for l := len(layers) - 2; l >= 0; l-- {
+			reorder(layers[l], down)
+		}
+	}
+
+	// Each layer is centered horizontally, and as tall as its tallest node.
+	xs := make([]float64, len(layerOf))
+	ys := make([]float64, len(layerOf))
+	layerWidths := make([]float64, len(layers))
+	for l, layer := range layers {
+		for i, v := range layer {
+			if i > 0 {
+				layerWidths[l] += graphNodeGap
+			}
+			layerWidths[l] += widths[v]
+		}
+		g.Width = max(g.Width, layerWidths[l])
+	}
+	y := float64(graphMargin)
+	for l, layer := range layers {
+		height := 0.0
+		for _, v := range layer {
+			height = max(height, heights[v])
+		}
+		x := graphMargin + (g.Width-layerWidths[l])/2
+		for _, v := range layer {
+			xs[v] = x + widths[v]/2
+			ys[v] = y + height/2
+			x += widths[v] + graphNodeGap
+		}
+		y += height + graphLayerGap
+	}
+	g.Width += 2 * graphMargin
+	g.Height = y - graphLayerGap + graphMargin
+
+	for i := range g.Nodes {
+		g.Nodes[i].X, g.Nodes[i].Y = xs[i], ys[i]
+		g.Nodes[i].Width, g.Nodes[i].Height = widths[i], heights[i]
+	}
+	for i, chain := range chains {
+		from, to := g.Nodes[g.Edges[i].From], g.Nodes[g.Edges[i].To]
+		points := []graphPoint{{from.X, from.Y + from.Height/2}}
+		for _, v := range chain[1 : len(chain)-1] {
+			points = append(points, graphPoint{xs[v], ys[v]})
+		}
+		g.Edges[i].Points = append(points, graphPoint{to.X, to.Y - to.Height/2})
+	}
+}
+
+// SVG renders the laid out graph. Nodes with a link are clickable.
+func (g *depGraph) SVG() template.HTML {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg id="dag-svg" class="dag" xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %.1f %.1f" role="img" aria-label="Module dependency graph">`+"\n",
+		g.Width, g.Height)
+	sb.WriteString(`<defs><marker id="dag-arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto"><path d="M0,0 L10,5 L0,10 z"/></marker></defs>` + "\n")
+	for _, e := range g.Edges {
+		sb.WriteString(`<path class="dag-edge" marker-end="url(#dag-arrow)" d="`)
+		fmt.Fprintf(&sb, "M%.1f,%.1f", e.Points[0].X, e.Points[0].Y)
+		for i := 1; i < len(e.Points); i++ {
+			p, q := e.Points[i-1], e.Points[i]
+			mid := (p.Y + q.Y) / 2
+			fmt.Fprintf(&sb, " C%.1f,%.1f %.1f,%.1f %.1f,%.1f", p.X, mid, q.X, mid, q.X, q.Y)
+		}
+		sb.WriteString(`"/>` + "\n")
+	}
+	for _, node := range g.Nodes {
+		if node.Link != "" {
+			fmt.Fprintf(&sb, `<a href="%s">`, template.HTMLEscapeString(node.Link))
+		}
+		class := "dag-node"
+		if node.Class != "" {
+			class += " " + node.Class
+		}
+		fmt.Fprintf(&sb, `<g id="dag-%s" class="%s">`, node.ID, class)
+		fmt.Fprintf(&sb, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="4"/>`,
+			node.X-node.Width/2, node.Y-node.Height/2, node.Width, node.Height)
+		sb.WriteString(`<text text-anchor="middle">`)
+		top := node.Y - node.Height/2 + graphPadding
+		for i, line := range node.Label {
+			// The baseline sits a little above the bottom of the line.
+			fmt.Fprintf(&sb, `<tspan x="%.1f" y="%.1f">%s</tspan>`,
+				node.X, top+float64(i+1)*graphLineHeight-4, template.HTMLEscapeString(line))
+		}
+		sb.WriteString(`</text></g>`)
+		if node.Link != "" {
+			sb.WriteString(`</a>`)
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("</svg>")
+	return template.HTML(sb.String())
+}
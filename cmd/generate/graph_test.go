@@ -0,0 +1,177 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func testGraphModule(name, version string, deps ...string) Module {
+	v := Version{Name: version}
+	for _, dep := range deps {
+		v.Dependencies = append(v.Dependencies, Dependency{Name: dep, Version: "1.0.0"})
+	}
+	return Module{Name: name, Versions: []Version{v}}
+}
+
+func graphNodeByID(t *testing.T, g *depGraph, id string) graphNode {
+	t.Helper()
+	for _, n := range g.Nodes {
+		if n.ID == id {
+			return n
+		}
+	}
+	t.Fatalf("no node %q in the graph", id)
+	return graphNode{}
+}
+
+func TestBuildGraph(t *testing.T) {
+	modules := []Module{
+		testGraphModule("app", "1.0.0", "lib", "util", "rules_go"),
+		testGraphModule("lib", "2.0.0", "util", "platforms"),
+		testGraphModule("util", "3.0.0"),
+	}
+	g := buildGraph(modules, cardLink, true)
+
+	if len(g.Nodes) != 4 {
+		t.Fatalf("expected 3 modules and the external node, got %d nodes", len(g.Nodes))
+	}
+	if got := graphNodeByID(t, g, "util").Class; got != "leaf" {
+		t.Errorf("expected util to be a leaf, got class %q", got)
+	}
+	external := graphNodeByID(t, g, "ExternalModules")
+	if external.Class != "inverted" || external.Link != "" {
+		t.Errorf("expected an inverted external node without a link, got %+v", external)
+	}
+	if want := []string{"platforms (1.0.0)", "rules_go (1.0.0)"}; strings.Join(external.Label, ",") != strings.Join(want, ",") {
+		t.Errorf("external node label = %q, want %q", external.Label, want)
+	}
+	if len(g.Edges) != 5 {
+		t.Errorf("expected 5 edges, got %d", len(g.Edges))
+	}
+
+	// Dependencies are below their dependents.
+	app, lib, util := graphNodeByID(t, g, "app"), graphNodeByID(t, g, "lib"), graphNodeByID(t, g, "util")
+	if !(app.Y < lib.Y && lib.Y < util.Y) {
+		t.Errorf("expected app above lib above util, got y = %v, %v, %v", app.Y, lib.Y, util.Y)
+	}
+	// The edge from app to util passes a virtual node in the layer of lib.
+	for _, e := range g.Edges {
+		if g.Nodes[e.From].ID == "app" && g.Nodes[e.To].ID == "util" && len(e.Points) != 3 {
+			t.Errorf("expected the edge from app to util to have 3 points, got %v", e.Points)
+		}
+	}
+	for _, n := range g.Nodes {
+		if n.X-n.Width/2 < 0 || n.X+n.Width/2 > g.Width || n.Y-n.Height/2 < 0 || n.Y+n.Height/2 > g.Height {
+			t.Errorf("node %s is outside of the graph", n.ID)
+		}
+	}
+}
+
+func TestBuildGraphNoOverlaps(t *testing.T) {
+	modules := []Module{
+		testGraphModule("a", "1", "c", "d", "e"),
+		testGraphModule("b", "1", "c", "e"),
+		testGraphModule("c", "1", "f"),
+		testGraphModule("d", "1", "f"),
+		testGraphModule("e", "1"),
+		testGraphModule("f", "1"),
+	}
+	g := buildGraph(modules, cardLink, true)
+	for i, m := range g.Nodes {
+		for _, n := range g.Nodes[i+1:] {
+			if m.Y == n.Y && m.X+m.Width/2 > n.X-n.Width/2 && n.X+n.Width/2 > m.X-m.Width/2 {
+				t.Errorf("nodes %s and %s overlap", m.ID, n.ID)
+			}
+		}
+	}
+}
+
+func TestBuildGraphCycle(t *testing.T) {
+	modules := []Module{
+		testGraphModule("a", "1", "b"),
+		testGraphModule("b", "1", "a"),
+	}
+	g := buildGraph(modules, cardLink, true)
+	if a, b := graphNodeByID(t, g, "a"), graphNodeByID(t, g, "b"); a.Y == b.Y {
+		t.Errorf("expected the cycle to be broken into two layers")
+	}
+	if len(g.Edges) != 2 {
+		t.Errorf("expected both edges of the cycle to be drawn, got %d", len(g.Edges))
+	}
+}
+
+func TestGraphSVG(t *testing.T) {
+	modules := []Module{
+		testGraphModule("my-app", "1.0.0", "<lib>"),
+		testGraphModule("<lib>", "2.0.0"),
+	}
+	svg := string(buildGraph(modules, modulePageLink, true).SVG())
+	for _, want := range []string{
+		`<svg id="dag-svg"`,
+		`<a href="my-app/index.html"><g id="dag-my_app" class="dag-node">`,
+		`<g id="dag-_lib_" class="dag-node leaf">`,
+		`&lt;lib&gt;</tspan>`,
+		`<path class="dag-edge"`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("expected the SVG to contain %q, got:\n%s", want, svg)
+		}
+	}
+	if strings.Contains(svg, "<lib>") {
+		t.Errorf("expected the labels to be escaped, got:\n%s", svg)
+	}
+}
+
+func TestBuildGraphDeprecated(t *testing.T) {
+	old := testGraphModule("old", "1.0.0")
+	old.Metadata.Deprecated = "Use new."
+	gone := testGraphModule("gone", "1.0.0")
+	gone.Metadata.Deprecated = "Unused."
+	modules := []Module{testGraphModule("app", "1.0.0", "old"), gone, old}
+
+	g := newDepGraph(modules, cardLink, false)
+	if got := graphNodeByID(t, g, "old").Class; got != "deprecated" {
+		t.Errorf("expected the deprecated dependency to be a deprecated node, got class %q", got)
+	}
+	for _, n := range g.Nodes {
+		if n.ID == "gone" || n.ID == "ExternalModules" {
+			t.Errorf("unexpected node %s", n.ID)
+		}
+	}
+	if len(g.Edges) != 1 {
+		t.Errorf("expected 1 edge, got %d", len(g.Edges))
+	}
+	if g := newDepGraph(modules, cardLink, true); graphNodeByID(t, g, "gone").Class != "deprecated" {
+		t.Errorf("expected all deprecated modules when they are included")
+	}
+
+	mermaid := g.Mermaid()
+	for _, want := range []string{"app -- \"jump\" --> old", "class old deprecated", "click old \"#card-old\""} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("expected the Mermaid graph to contain %q, got:\n%s", want, mermaid)
+		}
+	}
+}
+
+func TestGraphMermaid(t *testing.T) {
+	modules := []Module{
+		testGraphModule("app", "1.0.0", "lib", "platforms"),
+		testGraphModule("lib", "2.0.0"),
+	}
+	mermaid := newDepGraph(modules, cardLink, true).Mermaid()
+	for _, want := range []string{
+		"app[\"app\n1.0.0\"]",
+		"app -- \"jump\" --> lib",
+		"app -- \"jump\" --> ExternalModules",
+		"class lib leaf",
+		"class ExternalModules inverted",
+		"click lib \"#card-lib\"",
+	} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("expected the Mermaid graph to contain %q, got:\n%s", want, mermaid)
+		}
+	}
+	if strings.Contains(mermaid, "click ExternalModules") {
+		t.Errorf("expected no click on the external modules, got:\n%s", mermaid)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"html/template"
+	"strings"
+	"unicode"
+)
+
+var starlarkKeywords = map[string]bool{
+	"and": true, "break": true, "continue": true, "def": true, "elif": true,
+	"else": true, "for": true, "if": true, "in": true, "lambda": true,
+	"load": true, "not": true, "or": true, "pass": true, "return": true,
+	"True": true, "False": true, "None": true,
+}
+
+// highlightStarlark renders Starlark source, such as a MODULE.bazel file, as
+// HTML with comments, strings, numbers, keywords and calls wrapped in spans
+// with the classes hl-comment, hl-string, hl-number, hl-keyword and hl-call.
+func highlightStarlark(src string) template.HTML {
+	var sb strings.Builder
+	span := func(class, text string) {
+		sb.WriteString(`<span class="` + class + `">`)
+		sb.WriteString(template.HTMLEscapeString(text))
+		sb.WriteString(`</span>`)
+	}
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '#':
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			span("hl-comment", src[i:i+end])
+			i += end
+		case c == '"' || c == '\'':
+			end := stringEnd(src, i)
+			span("hl-string", src[i:end])
+			i = end
+		case isIdentStart(c):
+			end := i
+			for end < len(src) && isIdentPart(src[end]) {
+				end++
+			}
+			word := src[i:end]
+			switch {
+			case starlarkKeywords[word]:
+				span("hl-keyword", word)
+			case end < len(src) && src[end] == '(':
+				span("hl-call", word)
+			default:
+				sb.WriteString(template.HTMLEscapeString(word))
+			}
+			i = end
+		case c >= '0' && c <= '9':
+			end := i
+			for end < len(src) && (isIdentPart(src[end]) || src[end] == '.') {
+				end++
+			}
+			span("hl-number", src[i:end])
+			i = end
+		default:
+			sb.WriteString(template.HTMLEscapeString(src[i : i+1]))
+			i++
+		}
+	}
+	return template.HTML(sb.String())
+}
+
+// stringEnd returns the index just past the string literal that starts at
+// src[start], which may be triple-quoted. An unterminated string extends to
+// the end of the line, or of src for triple-quoted strings.
+func stringEnd(src string, start int) int {
+	quote := src[start : start+1]
+	if strings.HasPrefix(src[start:], strings.Repeat(quote, 3)) {
+		quote = strings.Repeat(quote, 3)
+	}
+	for i := start + len(quote); i < len(src); i++ {
+		switch {
+		case src[i] == '\\':
+			i++
+		case src[i] == '\n' && len(quote) == 1:
+			return i
+		case strings.HasPrefix(src[i:], quote):
+			return i + len(quote)
+		}
+	}
+	return len(src)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || unicode.IsLetter(rune(c))
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// highlightDiff renders a unified diff as HTML, with one span per line.
+// Added, removed, hunk header and file header lines have the classes
+// diff-add, diff-del, diff-hunk and diff-file.
+func highlightDiff(diff string) template.HTML {
+	var sb strings.Builder
+	for _, line := range splitLines(diff) {
+		class := ""
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"),
+			strings.HasPrefix(line, "diff "), strings.HasPrefix(line, "====="),
+			strings.HasPrefix(line, "index "):
+			class = "diff-file"
+		case strings.HasPrefix(line, "@@"):
+			class = "diff-hunk"
+		case strings.HasPrefix(line, "+"):
+			class = "diff-add"
+		case strings.HasPrefix(line, "-"):
+			class = "diff-del"
+		}
+		if class == "" {
+			sb.WriteString(template.HTMLEscapeString(line))
+		} else {
+			sb.WriteString(`<span class="` + class + `">`)
+			sb.WriteString(template.HTMLEscapeString(line))
+			sb.WriteString(`</span>`)
+		}
+		sb.WriteString("\n")
+	}
+	return template.HTML(sb.String())
+}
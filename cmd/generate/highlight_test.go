@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestHighlightStarlark(t *testing.T) {
+	got := string(highlightStarlark(`# The module.
+module(name = "a<b>", version = '1.0', compatibility_level = 1)
+x = """multi
+"line" """
+if True: pass
+`))
+	want := `<span class="hl-comment"># The module.</span>
+<span class="hl-call">module</span>(name = <span class="hl-string">&#34;a&lt;b&gt;&#34;</span>, version = <span class="hl-string">&#39;1.0&#39;</span>, compatibility_level = <span class="hl-number">1</span>)
+x = <span class="hl-string">&#34;&#34;&#34;multi
+&#34;line&#34; &#34;&#34;&#34;</span>
+<span class="hl-keyword">if</span> <span class="hl-keyword">True</span>: <span class="hl-keyword">pass</span>
+`
+	if got != want {
+		t.Errorf("highlightStarlark() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHighlightStarlarkUnterminatedString(t *testing.T) {
+	got := string(highlightStarlark("x = \"abc\ny"))
+	want := "x = <span class=\"hl-string\">&#34;abc</span>\ny"
+	if got != want {
+		t.Errorf("highlightStarlark() = %q, want %q", got, want)
+	}
+}
+
+func TestHighlightDiff(t *testing.T) {
+	got := string(highlightDiff("--- a/f\n+++ b/f\n@@ -1 +1 @@\n-old\n+<new>\n same\n"))
+	want := `<span class="diff-file">--- a/f</span>
+<span class="diff-file">+++ b/f</span>
+<span class="diff-hunk">@@ -1 +1 @@</span>
+<span class="diff-del">-old</span>
+<span class="diff-add">+&lt;new&gt;</span>
+ same
+`
+	if got != want {
+		t.Errorf("highlightDiff() =\n%s\nwant:\n%s", got, want)
+	}
+}
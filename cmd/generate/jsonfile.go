@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// jsonObject is a JSON object that remembers the order of its keys, so that
+// registry files can be edited without reordering them.
+type jsonObject struct {
+	keys   []string
+	values map[string]json.RawMessage
+}
+
+func (o *jsonObject) UnmarshalJSON(b []byte) error {
+	d := json.NewDecoder(bytes.NewReader(b))
+	t, err := d.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := t.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected a JSON object")
+	}
+	o.keys = nil
+	o.values = make(map[string]json.RawMessage)
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		key := t.(string)
+		var value json.RawMessage
+		if err := d.Decode(&value); err != nil {
+			return err
+		}
+		if _, ok := o.values[key]; !ok {
+			o.keys = append(o.keys, key)
+		}
+		o.values[key] = value
+	}
+	_, err = d.Token()
+	return err
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(o.values[key])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Has reports whether the object contains key.
+func (o *jsonObject) Has(key string) bool {
+	_, ok := o.values[key]
+	return ok
+}
+
+// Get decodes the value at key into v. A missing key leaves v unchanged.
+func (o *jsonObject) Get(key string, v interface{}) error {
+	value, ok := o.values[key]
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal(value, v); err != nil {
+		return fmt.Errorf("failed to decode %q: %w", key, err)
+	}
+	return nil
+}
+
+// Set replaces the value at key, appending the key if it is new.
+func (o *jsonObject) Set(key string, v interface{}) error {
+	value, err := marshalJSON(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %q: %w", key, err)
+	}
+	if o.values == nil {
+		o.values = make(map[string]json.RawMessage)
+	}
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = bytes.TrimSpace(value)
+	return nil
+}
+
+// Delete removes key from the object.
+func (o *jsonObject) Delete(key string) {
+	if _, ok := o.values[key]; !ok {
+		return
+	}
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Reorder moves the keys in order to the front of the object, in that order.
+// The remaining keys keep their relative order after them.
+func (o *jsonObject) Reorder(order []string) {
+	keys := make([]string, 0, len(o.keys))
+	seen := make(map[string]bool)
+	for _, key := range order {
+		if o.Has(key) {
+			keys = append(keys, key)
+			seen[key] = true
+		}
+	}
+	for _, key := range o.keys {
+		if !seen[key] {
+			keys = append(keys, key)
+		}
+	}
+	o.keys = keys
+}
+
+// SortKeys sorts the keys of the object alphabetically.
+func (o *jsonObject) SortKeys() {
+	sort.Strings(o.keys)
+}
+
+func readJSONObject(path string) (*jsonObject, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var o jsonObject
+	if err := json.Unmarshal(content, &o); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &o, nil
+}
+
+func writeJSONObject(path string, o *jsonObject) error {
+	content, err := marshalJSON(o)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// marshalJSON encodes v the way registry files are formatted: 4-space
+// indentation, no HTML escaping and a trailing newline.
+func marshalJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	e := json.NewEncoder(&buf)
+	e.SetEscapeHTML(false)
+	e.SetIndent("", "    ")
+	if err := e.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
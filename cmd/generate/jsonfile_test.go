@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONObject_PreservesKeyOrder(t *testing.T) {
+	var o jsonObject
+	if err := json.Unmarshal([]byte(`{"b": 1, "a": {"y": [1, 2], "x": "<url>"}}`), &o); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Set("c", []string{"z"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Set("b", 2); err != nil {
+		t.Fatal(err)
+	}
+	got, err := marshalJSON(&o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+    "b": 2,
+    "a": {
+        "y": [
+            1,
+            2
+        ],
+        "x": "<url>"
+    },
+    "c": [
+        "z"
+    ]
+}
+`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	o.Delete("a")
+	var b int
+	if err := o.Get("b", &b); err != nil || b != 2 || o.Has("a") {
+		t.Errorf("unexpected object after Delete: %v", o.keys)
+	}
+}
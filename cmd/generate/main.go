@@ -2,17 +2,21 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"html/template"
 	"io"
-	"io/ioutil"
+	"io/fs"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 )
 
 var (
@@ -30,20 +34,72 @@ type Module struct {
 	Name     string
 	Metadata Metadata
 	Versions []Version
+	// Overlaid is set if any of the module's files come from an overlay
+	// directory.
+	Overlaid bool
 }
 
 type Metadata struct {
-	Homepage       string            `json:"homepage"`
+	Homepage string `json:"homepage"`
+	// Description says what the module is for, in a sentence or two.
+	Description string `json:"description,omitempty"`
+	// Tags are topics of the module, e.g. "hdl" or "toolchain". The first
+	// tag is its category.
+	Tags           []string          `json:"tags,omitempty"`
+	Maintainers    []Maintainer      `json:"maintainers"`
 	Repo           []string          `json:"repository"`
 	Versions       []string          `json:"versions"`
 	YankedVersions map[string]string `json:"yanked_versions"`
+	Deprecated     string            `json:"deprecated,omitempty"`
+	// ReplacedBy names the module that replaces a deprecated module.
+	ReplacedBy string `json:"replaced_by,omitempty"`
+	// PublishedAt maps versions to their publication dates, as RFC 3339
+	// timestamps or plain dates. It overrides the dates from the changelog.
+	PublishedAt map[string]string `json:"published_at,omitempty"`
+}
+
+type Maintainer struct {
+	Name         string `json:"name,omitempty"`
+	Email        string `json:"email,omitempty"`
+	GitHub       string `json:"github,omitempty"`
+	GitHubUserID int    `json:"github_user_id,omitempty"`
 }
 
 type Version struct {
 	Name         string
 	ModuleFile   string
 	SourceFile   string
+	Source       Source
 	Dependencies []Dependency
+	Attestations *Attestations
+	// AttestationsFile is the content of attestations.json, if any. The
+	// Attestations are only set if it is valid.
+	AttestationsFile string
+	Overlaid         bool
+	Presubmit        string
+	Patches          []File
+	Overlay          []File
+	// Published is when the version was published, or zero if unknown.
+	Published time.Time
+}
+
+// File is a file of a module version, such as a patch.
+type File struct {
+	Name    string
+	Content string
+}
+
+// Source is the content of source.json. The fields are in the order that
+// fmt puts them in.
+type Source struct {
+	Integrity   string            `json:"integrity"`
+	StripPrefix string            `json:"strip_prefix,omitempty"`
+	URL         string            `json:"url"`
+	ArchiveType string            `json:"archive_type,omitempty"`
+	DocsURL     string            `json:"docs_url,omitempty"`
+	Patches     map[string]string `json:"patches,omitempty"`
+	PatchStrip  int               `json:"patch_strip,omitempty"`
+	Overlay     map[string]string `json:"overlay,omitempty"`
 }
 
 type Dependency struct {
@@ -53,19 +109,88 @@ type Dependency struct {
 }
 
 type TemplateData struct {
+	Title   string
+	Root    string
+	Site    SiteOptions
 	Modules []Module
-	Mermaid template.HTML
+	// Graph is the dependency graph, as SVG.
+	Graph template.HTML
+	// SearchIndex is what the search box searches.
+	SearchIndex []searchEntry
+	// Facets are what the modules can be filtered and grouped by.
+	Facets Facets
+	// ModulePages is set if a detail page is generated for each module.
+	ModulePages bool
+	// LiveReload makes the page reload itself when the registry changes
+	// on disk. Only set when the page is served by the serve subcommand.
+	LiveReload bool
+	// WhatsNew holds the latest changelog events, if there is a changelog.
+	WhatsNew *ChangelogPageData
+	// Feed is set if an Atom feed is generated next to the page.
+	Feed bool
+}
+
+// subcommands maintain the registry contents, as opposed to the default
+// invocation which renders them.
+var subcommands = map[string]func(args []string) error{
+	"add-module":      runAddModule,
+	"add-version":     runAddVersion,
+	"changelog":       runChangelog,
+	"deprecate":       runDeprecate,
+	"diff":            runDiff,
+	"export-bundle":   runExportBundle,
+	"fmt":             runFmt,
+	"registry-config": runRegistryConfig,
+	"serve":           runServe,
+	"sync-metadata":   runSyncMetadata,
+	"unyank":          runUnyank,
+	"yank":            runYank,
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				log.Printf("error: %v", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	var (
 		modulesDir string
 		outputFile string
 		mode       string
+
+		includeDeprecated bool
+		modulePages       bool
+		overlayDirs       stringsFlag
+		changelog         string
+		feed              bool
+		badges            bool
+		siteURL           string
+		analyticsID       string
+		configPath        string
+		cacheDir          string
+		site              SiteOptions
 	)
 	flag.StringVar(&modulesDir, "modules_dir", "", "The path to the modules directory.")
 	flag.StringVar(&outputFile, "output", "", "The file name to output")
-	flag.StringVar(&mode, "mode", "html", "The output mode: html or mermaid")
+	flag.StringVar(&mode, "mode", "html", "The output mode: html, mermaid or json")
+	flag.BoolVar(&includeDeprecated, "dag_include_deprecated", false, "Include all deprecated modules in the dependency graph, not only those that other modules depend on.")
+	flag.BoolVar(&modulePages, "module_pages", false, "Also generate a detail page for each module, in <module>/index.html next to the output.")
+	flag.Var(&overlayDirs, "overlay_dir", "A directory of modules to merge over the modules directory. May be repeated.")
+	flag.StringVar(&changelog, "changelog", "", "The changelog file written by the changelog subcommand. If set, a changelog.html page is also generated next to the output.")
+	flag.BoolVar(&feed, "feed", false, "Also generate an Atom feed of the releases in the changelog, in feed.xml next to the output.")
+	flag.BoolVar(&badges, "badges", false, "Also generate an SVG badge with the latest version of each module, in badges/<module>.svg next to the output.")
+	flag.StringVar(&siteURL, "site_url", "", "The URL at which the output is published, for the links in the feed. Overrides the config file, defaults to "+defaultBranding.SiteURL)
+	flag.BoolVar(&site.SelfContained, "self_contained", false, "Generate pages that load nothing from other sites, with Bootstrap and its icons included.")
+	flag.StringVar(&site.AssetsDir, "assets_dir", "", "Write the stylesheets and scripts into this directory next to the output, instead of inlining them into each page.")
+	flag.StringVar(&analyticsID, "analytics_id", "", "The Google Analytics measurement ID. Overrides the config file. Analytics are off if empty.")
+	flag.StringVar(&configPath, "config", "", "A JSON config file whose \"site\" key sets the title, links, footer and analytics of the pages.")
+	flag.StringVar(&cacheDir, "cache_dir", "", "The archive cache, as for export-bundle. Modules without a description are described by the README in their latest archive, and attestations are checked against the artifacts, if they are in the cache.")
+	flag.StringVar(&site.TemplateDir, "template_dir", "", "A directory of *.html templates that replace the built-in templates of the same name.")
 	flag.Parse()
 	if modulesDir == "" {
 		log.Printf("flag --modules_dir=... is required")
@@ -75,148 +200,199 @@ func main() {
 		log.Printf("flag --output=... is required")
 		os.Exit(1)
 	}
-
-	if err := run(modulesDir, outputFile, mode); err != nil {
-		log.Printf("error: %v", err)
-		os.Exit(1)
+	if configPath != "" {
+		config, err := loadConfig(configPath)
+		if err != nil {
+			log.Printf("error: %v", err)
+			os.Exit(1)
+		}
+		site.Branding = config.Site
 	}
-}
-
-func run(modulesDir, outputFile, mode string) error {
-	modules, err := findModules(modulesDir)
-	if err != nil {
-		log.Fatalf("failed to find modules: %v", err)
+	if siteURL != "" {
+		site.SiteURL = siteURL
+	}
+	if analyticsID != "" {
+		site.AnalyticsID = analyticsID
 	}
 
-	o, err := os.Create(outputFile)
+	err := run(runOptions{
+		ModulesDir:        modulesDir,
+		OverlayDirs:       overlayDirs,
+		OutputFile:        outputFile,
+		Mode:              mode,
+		IncludeDeprecated: includeDeprecated,
+		ModulePages:       modulePages,
+		Changelog:         changelog,
+		Feed:              feed,
+		Badges:            badges,
+		CacheDir:          cacheDir,
+		Site:              site,
+	})
 	if err != nil {
-		log.Printf("could not create: %v: %v", outputFile, err)
+		log.Printf("error: %v", err)
+		os.Exit(1)
 	}
+}
 
-	mermaid := buildMermaid(modules)
+// stringsFlag collects the values of a flag that may be repeated.
+type stringsFlag []string
 
-	if mode == "mermaid" {
-		if _, err := o.Write([]byte(mermaid)); err != nil {
-			log.Fatalf("failed to write mermaid: %v", err)
-		}
-	} else {
-		if err := generateHTML(modules, mermaid, o); err != nil {
-			log.Fatalf("failed to generate HTML: %v", err)
-		}
-	}
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
 
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
 	return nil
 }
 
-func buildMermaid(modules []Module) string {
-	var sb strings.Builder
-	sb.WriteString(`---
-config:
-  layout: elk
-  elk:
-    mergeEdges: true
----
-`)
-	sb.WriteString("flowchart TB\n")
-
-	registryLatest := make(map[string]string)
-	for _, m := range modules {
-		if len(m.Versions) > 0 {
-			registryLatest[m.Name] = m.Versions[0].Name
-		}
-	}
-
-	nodes := make(map[string]bool)
-	edges := make(map[string]bool)
-	allNodes := make(map[string]bool)
+type runOptions struct {
+	ModulesDir        string
+	OverlayDirs       []string
+	OutputFile        string
+	Mode              string
+	IncludeDeprecated bool
+	ModulePages       bool
+	Changelog         string
+	Feed              bool
+	Badges            bool
+	CacheDir          string
+	Site              SiteOptions
+}
 
-	// Collect all external modules
-	externalModulesSet := make(map[string]string) // Name -> Version
+func run(opts runOptions) error {
+	fsys := newRegistryFS(opts.ModulesDir, opts.OverlayDirs)
+	modules, err := findRegistryModules(fsys)
+	if err != nil {
+		log.Fatalf("failed to find modules: %v", err)
+	}
 	for _, m := range modules {
-		if len(m.Versions) == 0 {
+		if err := validateModule(m); err != nil {
+			log.Printf("module %s has problems:\n%v", m.Name, err)
+		}
+		if opts.CacheDir == "" {
 			continue
 		}
-		latest := m.Versions[0]
-		for _, dep := range latest.Dependencies {
-			if _, ok := registryLatest[dep.Name]; !ok {
-				externalModulesSet[dep.Name] = dep.Version
+		for _, v := range m.Versions {
+			if err := verifyAttestations(v, opts.CacheDir); err != nil {
+				log.Printf("module %s version %s has attestation problems:\n%v", m.Name, v.Name, err)
 			}
 		}
 	}
 
-	var externalNames []string
-	for name := range externalModulesSet {
-		externalNames = append(externalNames, name)
+	var events []ChangeEvent
+	if opts.Changelog != "" {
+		events, err = loadChangelog(opts.Changelog)
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Printf("no changelog at %s, the changelog will be empty", opts.Changelog)
+		} else if err != nil {
+			log.Fatalf("failed to load changelog: %v", err)
+		}
+	}
+	setPublished(modules, events)
+	if opts.CacheDir != "" {
+		describeFromREADME(modules, opts.CacheDir)
 	}
-	sort.Strings(externalNames)
 
-	escape := func(s string) string {
-		return strings.ReplaceAll(s, "\"", "\\\"")
+	o, err := os.Create(opts.OutputFile)
+	if err != nil {
+		log.Printf("could not create: %v: %v", opts.OutputFile, err)
 	}
 
-	externalNodeID := "ExternalModules"
-	if len(externalNames) > 0 {
-		labelLabels := []string{}
-		for _, name := range externalNames {
-			labelLabels = append(labelLabels, fmt.Sprintf("%s (%s)", name, externalModulesSet[name]))
-		}
-		label := strings.Join(labelLabels, "\n")
-		sb.WriteString(fmt.Sprintf("    %s[\"%s\"]\n", externalNodeID, escape(label)))
-		sb.WriteString(fmt.Sprintf("    class %s inverted\n", externalNodeID))
-		allNodes[externalNodeID] = true
+	link := cardLink
+	if opts.ModulePages {
+		link = modulePageLink
 	}
 
-	for _, m := range modules {
-		if len(m.Versions) == 0 {
-			continue
+	if opts.Mode == "mermaid" {
+		if _, err := o.Write([]byte(buildMermaidWithLinks(modules, link, opts.IncludeDeprecated))); err != nil {
+			log.Fatalf("failed to write mermaid: %v", err)
+		}
+	} else if opts.Mode == "json" {
+		defer o.Close()
+		if err := writeModulesJSON(modules, o); err != nil {
+			log.Fatalf("failed to write JSON: %v", err)
+		}
+	} else {
+		defer o.Close()
+		tmpl, err := parseTemplates(opts.Site.TemplateDir)
+		if err != nil {
+			log.Fatalf("failed to parse HTML templates: %v", err)
 		}
-		latest := m.Versions[0]
-		mID := sanitizeID(m.Name)
-		if !nodes[mID] {
-			sb.WriteString(fmt.Sprintf("    %s[\"%s\n%s\"]\n", mID, escape(m.Name), escape(latest.Name)))
-			nodes[mID] = true
-			allNodes[mID] = true
-		}
-
-		hasInternalDeps := false
-		for _, dep := range latest.Dependencies {
-			var depID string
-			if _, ok := registryLatest[dep.Name]; ok {
-				depID = sanitizeID(dep.Name)
-				hasInternalDeps = true
-				if !nodes[depID] {
-					version := registryLatest[dep.Name]
-					sb.WriteString(fmt.Sprintf("    %s[\"%s\n%s\"]\n", depID, escape(dep.Name), escape(version)))
-					nodes[depID] = true
-					allNodes[depID] = true
-				}
-			} else {
-				depID = externalNodeID
+		data := TemplateData{
+			Site:        opts.Site,
+			Modules:     modules,
+			Graph:       buildGraph(modules, link, opts.IncludeDeprecated).SVG(),
+			SearchIndex: buildSearchIndex(modules),
+			Facets:      buildFacets(modules),
+			ModulePages: opts.ModulePages,
+			Feed:        opts.Feed,
+		}
+		if len(events) > 0 {
+			data.WhatsNew = &ChangelogPageData{
+				Events:      events[:min(len(events), whatsNewCount)],
+				ModulePages: opts.ModulePages,
 			}
-
-			edgeID := fmt.Sprintf("%s->%s", mID, depID)
-			if !edges[edgeID] {
-				// Use "jump" label on edges for navigation
-				sb.WriteString(fmt.Sprintf("    %s -- \"jump\" --> %s\n", mID, depID))
-				edges[edgeID] = true
+		}
+		if err := writeHTML(tmpl, data, o); err != nil {
+			log.Fatalf("failed to generate HTML: %v", err)
+		}
+		if err := writeAssets(filepath.Dir(opts.OutputFile), opts.Site); err != nil {
+			log.Fatalf("failed to write assets: %v", err)
+		}
+		if opts.Changelog != "" {
+			if err := writeChangelogPage(tmpl, events, opts.ModulePages, filepath.Dir(opts.OutputFile), opts.Site); err != nil {
+				log.Fatalf("failed to generate the changelog page: %v", err)
 			}
 		}
-
-		if !hasInternalDeps {
-			sb.WriteString(fmt.Sprintf("    class %s leaf\n", mID))
+		if opts.Feed {
+			feed := buildFeed(modules, opts.Site.Branding)
+			if err := writeFeedFile(feed, filepath.Dir(opts.OutputFile)); err != nil {
+				log.Fatalf("failed to generate the feed: %v", err)
+			}
 		}
-	}
-
-	for nID := range allNodes {
-		if nID == externalNodeID {
-			continue
+		var declared []string
+		if opts.Badges || opts.ModulePages {
+			declared, err = declaredModules(fsys)
+			if err != nil {
+				log.Fatalf("failed to find modules: %v", err)
+			}
+		}
+		if opts.Badges {
+			if err := writeBadges(modules, declared, filepath.Dir(opts.OutputFile), opts.Site.Branding); err != nil {
+				log.Fatalf("failed to generate the badges: %v", err)
+			}
+		}
+		if opts.ModulePages {
+			if err := writeModulePages(tmpl, modules, declared, filepath.Dir(opts.OutputFile), opts.Site); err != nil {
+				log.Fatalf("failed to generate module pages: %v", err)
+			}
 		}
-		sb.WriteString(fmt.Sprintf("    click %s \"#card-%s\"\n", nID, nID))
 	}
 
-	sb.WriteString("    classDef inverted fill:#333,color:#fff\n")
-	sb.WriteString("    classDef leaf fill:#28a745,color:#fff\n")
-	return sb.String()
+	return nil
+}
+
+// cardLink is the link to the card of a module on the index page.
+func cardLink(name string) string {
+	return "#card-" + sanitizeID(name)
+}
+
+func buildMermaid(modules []Module) string {
+	return buildMermaidWithLinks(modules, cardLink, true)
+}
+
+// buildMermaidWithLinks builds the dependency graph, see newDepGraph, with
+// clicks on a module going to link(module name).
+func buildMermaidWithLinks(modules []Module, link func(name string) string, includeDeprecated bool) string {
+	return newDepGraph(modules, link, includeDeprecated).Mermaid()
+}
+
+func repoURL(s string) string {
+	if strings.HasPrefix(s, "github:") {
+		return "https://github.com/" + strings.TrimPrefix(s, "github:")
+	}
+	return s
 }
 
 var sanitizeRe = regexp.MustCompile("[^a-zA-Z0-9_]")
@@ -226,9 +402,13 @@ func sanitizeID(s string) string {
 }
 
 func findModules(dir string) ([]Module, error) {
+	return findRegistryModules(newRegistryFS(dir, nil))
+}
+
+func findRegistryModules(fsys overlayFS) ([]Module, error) {
 	var modules []Module
 
-	moduleDirs, err := ioutil.ReadDir(dir)
+	moduleDirs, err := fs.ReadDir(fsys, ".")
 	if err != nil {
 		return nil, fmt.Errorf("failed to read modules directory: %w", err)
 	}
@@ -238,33 +418,12 @@ func findModules(dir string) ([]Module, error) {
 			continue
 		}
 
-		modulePath := filepath.Join(dir, moduleDir.Name())
-		metadataPath := filepath.Join(modulePath, "metadata.json")
-
-		metadataFile, err := os.Open(metadataPath)
-		if err != nil {
-			log.Printf("skipping directory %s: metadata.json not found", moduleDir.Name())
-			continue
-		}
-		defer metadataFile.Close()
-
-		var metadata Metadata
-		if err := json.NewDecoder(metadataFile).Decode(&metadata); err != nil {
-			log.Printf("skipping directory %s: failed to parse metadata.json: %v", moduleDir.Name(), err)
-			continue
-		}
-
-		versions, err := findVersions(modulePath)
+		module, err := loadRegistryModule(fsys, moduleDir.Name())
 		if err != nil {
-			log.Printf("skipping directory %s: failed to find versions: %v", moduleDir.Name(), err)
+			log.Printf("skipping directory %s: %v", moduleDir.Name(), err)
 			continue
 		}
-
-		modules = append(modules, Module{
-			Name:     moduleDir.Name(),
-			Metadata: metadata,
-			Versions: versions,
-		})
+		modules = append(modules, module)
 	}
 
 	sort.Slice(modules, func(i, j int) bool {
@@ -274,36 +433,119 @@ func findModules(dir string) ([]Module, error) {
 	return modules, nil
 }
 
-func findVersions(modulePath string) ([]Version, error) {
+// declaredModules returns the names of the module directories that have a
+// metadata.json, which modules/BUILD.bazel declares outputs for, whether or
+// not they load.
+func declaredModules(fsys fs.FS) ([]string, error) {
+	matches, err := fs.Glob(fsys, "*/metadata.json")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, m := range matches {
+		names = append(names, path.Dir(m))
+	}
+	return names, nil
+}
+
+// checkDeclared returns an error if any of the declared modules is missing
+// from modules, since its declared outputs would then not be written.
+func checkDeclared(modules []Module, declared []string) error {
+	loaded := make(map[string]bool)
+	for _, m := range modules {
+		loaded[m.Name] = true
+	}
+	var skipped []string
+	for _, name := range declared {
+		if !loaded[name] {
+			skipped = append(skipped, name)
+		}
+	}
+	if len(skipped) > 0 {
+		return fmt.Errorf("modules with declared outputs were skipped: %s", strings.Join(skipped, ", "))
+	}
+	return nil
+}
+
+func loadModule(dir, name string) (Module, error) {
+	return loadRegistryModule(newRegistryFS(dir, nil), name)
+}
+
+func loadRegistryModule(fsys overlayFS, name string) (Module, error) {
+	metadataFile, err := fsys.Open(path.Join(name, "metadata.json"))
+	if err != nil {
+		return Module{}, fmt.Errorf("metadata.json not found")
+	}
+	defer metadataFile.Close()
+
+	var metadata Metadata
+	if err := json.NewDecoder(metadataFile).Decode(&metadata); err != nil {
+		return Module{}, fmt.Errorf("failed to parse metadata.json: %w", err)
+	}
+	if err := loadAbout(fsys, name, &metadata); err != nil {
+		return Module{}, err
+	}
+
+	versions, err := findVersions(fsys, name)
+	if err != nil {
+		return Module{}, fmt.Errorf("failed to find versions: %w", err)
+	}
+
+	// Versions added by an overlay are not listed in the registry's
+	// metadata.json.
+	for _, v := range versions {
+		if v.Overlaid && !slices.Contains(metadata.Versions, v.Name) {
+			metadata.Versions = append(metadata.Versions, v.Name)
+		}
+	}
+	sortVersions(metadata.Versions)
+
+	for i, v := range versions {
+		if date, ok := metadata.PublishedAt[v.Name]; ok {
+			// Malformed dates are reported by validateModule.
+			versions[i].Published, _ = parsePublishedAt(date)
+		}
+	}
+
+	return Module{
+		Name:     name,
+		Metadata: metadata,
+		Versions: versions,
+		Overlaid: fsys.Overlaid(name),
+	}, nil
+}
+
+func findVersions(fsys overlayFS, modulePath string) ([]Version, error) {
 	var versions []Version
 
-	versionDirs, err := ioutil.ReadDir(modulePath)
+	versionDirs, err := fs.ReadDir(fsys, modulePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read module directory: %w", err)
 	}
 
 	for _, versionDir := range versionDirs {
-		if !versionDir.IsDir() {
+		// Hidden directories hold unfinished versions, see addVersion.
+		if !versionDir.IsDir() || strings.HasPrefix(versionDir.Name(), ".") {
 			continue
 		}
 
-		versionPath := filepath.Join(modulePath, versionDir.Name())
-		moduleFilePath := filepath.Join(versionPath, "MODULE.bazel")
-		sourceFilePath := filepath.Join(versionPath, "source.json")
+		versionPath := path.Join(modulePath, versionDir.Name())
+		moduleFilePath := path.Join(versionPath, "MODULE.bazel")
+		sourceFilePath := path.Join(versionPath, "source.json")
 
-		if _, err := os.Stat(moduleFilePath); os.IsNotExist(err) {
+		if _, err := fs.Stat(fsys, moduleFilePath); errors.Is(err, fs.ErrNotExist) {
 			continue
 		}
-		if _, err := os.Stat(sourceFilePath); os.IsNotExist(err) {
+		if _, err := fs.Stat(fsys, sourceFilePath); errors.Is(err, fs.ErrNotExist) {
 			continue
 		}
 
-		moduleFileContent, err := ioutil.ReadFile(moduleFilePath)
+		moduleFileContent, err := fs.ReadFile(fsys, moduleFilePath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read MODULE.bazel: %w", err)
 		}
 
-		sourceFileContent, err := ioutil.ReadFile(sourceFilePath)
+		sourceFileContent, err := fs.ReadFile(fsys, sourceFilePath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read source.json: %w", err)
 		}
@@ -328,264 +570,197 @@ func findVersions(modulePath string) ([]Version, error) {
 			return deps[i].Name < deps[j].Name
 		})
 
+		var source Source
+		if err := json.Unmarshal(sourceFileContent, &source); err != nil {
+			return nil, fmt.Errorf("failed to parse source.json: %w", err)
+		}
+
+		var attestations *Attestations
+		attestationsContent, err := fs.ReadFile(fsys, path.Join(versionPath, attestationsFile))
+		if err == nil {
+			// validateModule reports invalid attestations.
+			attestations, _ = parseAttestations(attestationsContent, source)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read %s: %w", attestationsFile, err)
+		}
+
+		presubmit, err := fs.ReadFile(fsys, path.Join(versionPath, "presubmit.yml"))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read presubmit.yml: %w", err)
+		}
+
+		patches, err := readFiles(fsys, path.Join(versionPath, "patches"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read patches: %w", err)
+		}
+
+		overlay, err := readFiles(fsys, path.Join(versionPath, "overlay"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read overlay: %w", err)
+		}
+
 		versions = append(versions, Version{
-			Name:         versionDir.Name(),
-			ModuleFile:   string(moduleFileContent),
-			SourceFile:   string(sourceFileContent),
-			Dependencies: deps,
+			Name:             versionDir.Name(),
+			ModuleFile:       string(moduleFileContent),
+			SourceFile:       string(sourceFileContent),
+			Source:           source,
+			Dependencies:     deps,
+			Attestations:     attestations,
+			AttestationsFile: string(attestationsContent),
+			Overlaid:         fsys.Overlaid(versionPath),
+			Presubmit:        string(presubmit),
+			Patches:          patches,
+			Overlay:          overlay,
 		})
 	}
 
 	sort.Slice(versions, func(i, j int) bool {
-		return versions[i].Name > versions[j].Name
+		return compareVersions(versions[i].Name, versions[j].Name) > 0
 	})
 
 	return versions, nil
 }
 
-func generateHTML(modules []Module, mermaid string, w io.WriteCloser) error {
+// readFiles reads the regular files in dir and below it, with names
+// relative to dir. A missing directory has no files.
+func readFiles(fsys fs.FS, dir string) ([]File, error) {
+	var files []File
+	err := fs.WalkDir(fsys, dir, func(name string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && name == dir {
+			return fs.SkipDir
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		files = append(files, File{
+			Name:    strings.TrimPrefix(name, dir+"/"),
+			Content: string(content),
+		})
+		return nil
+	})
+	return files, err
+}
+
+func generateHTML(modules []Module, graph string, w io.WriteCloser) error {
 	defer w.Close()
-	tmpl, err := template.New("index").Funcs(template.FuncMap{
-		"isURL": func(s string) bool {
-			return strings.HasPrefix(s, "http")
-		},
-		"sub": func(a, b int) int {
-			return a - b
-		},
-		"repoURL": func(s string) string {
-			if strings.HasPrefix(s, "github:") {
-				return "https://github.com/" + strings.TrimPrefix(s, "github:")
-			}
-			return s
-		},
-		"bazelDep": func(name, version string) string {
-			return fmt.Sprintf(`bazel_dep(name = "%s", version = "%s")`, name, version)
-		},
-		"isYanked": func(version string, metadata Metadata) bool {
-			_, ok := metadata.YankedVersions[version]
-			return ok
-		},
-		"sanitizeID": sanitizeID,
-	}).Parse(htmlTemplate)
+	tmpl, err := parseTemplates("")
 	if err != nil {
-		return fmt.Errorf("failed to parse HTML template: %w", err)
+		return err
 	}
-
-	var buf strings.Builder
-	data := TemplateData{
+	return writeHTML(tmpl, TemplateData{
 		Modules: modules,
-		Mermaid: template.HTML(mermaid),
-	}
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return fmt.Errorf("failed to execute HTML template: %w", err)
-	}
-
-	_, err = fmt.Fprintf(w, "%s", buf.String())
-	if err != nil {
-		return fmt.Errorf("failed to write output file: %w", err)
-	}
+		Graph:   template.HTML(graph),
+	}, w)
+}
 
-	return nil
+func writeHTML(tmpl *template.Template, data TemplateData, w io.Writer) error {
+	data.Site = data.Site.withDefaults()
+	return executeTemplate(tmpl, "index", data, w)
 }
 
 const htmlTemplate = `
 <!DOCTYPE html>
 <html lang="en">
 <head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Bazel Registry</title>
-    <link
-		href="https://cdn.jsdelivr.net/npm/bootstrap@5.3.0-alpha1/dist/css/bootstrap.min.css"
-		rel="stylesheet"
-	>
-    <link
-		href="https://cdn.jsdelivr.net/npm/bootstrap-icons@1.10.3/font/bootstrap-icons.css"
-		rel="stylesheet"
-	>
-	<link rel="icon" href="hdlfactory.png" type="image/png">
-	<!-- Google tag (gtag.js) -->
-	<script async src="https://www.googletagmanager.com/gtag/js?id=G-BKGTF9GD1K"></script>
-	<script>
-	  window.dataLayer = window.dataLayer || [];
-	  function gtag(){dataLayer.push(arguments);}
-	  gtag('js', new Date());
-
-	  gtag('config', 'G-BKGTF9GD1K');
-	</script>
-	<script>
-      function getPreferredTheme() {
-        const savedTheme = localStorage.getItem('theme');
-        if (savedTheme) {
-          return savedTheme;
-        }
-        return 'system';
-      }
-
-      function resolveTheme(theme) {
-        if (theme === 'system') {
-          const currentHour = new Date().getHours();
-          return (currentHour >= 19 || currentHour < 7) ? 'dark' : 'light';
-        }
-        return theme;
-      }
-
-      function updateThemeIcon(theme) {
-        const toggleBtn = document.getElementById('themeToggle');
-        if (toggleBtn) {
-          if (theme === 'light') {
-            toggleBtn.innerHTML = '<i class="bi bi-sun"></i>';
-          } else if (theme === 'dark') {
-            toggleBtn.innerHTML = '<i class="bi bi-moon-stars"></i>';
-          } else {
-            toggleBtn.innerHTML = '<i class="bi bi-circle-half"></i>';
-          }
-        }
-      }
-
-      function setTheme(theme) {
-        document.documentElement.setAttribute('data-bs-theme', resolveTheme(theme));
-        localStorage.setItem('theme', theme);
-        updateThemeIcon(theme);
-      }
-
-      setTheme(getPreferredTheme());
-
-      document.addEventListener('DOMContentLoaded', () => {
-        updateThemeIcon(getPreferredTheme());
-      });
-
-      function toggleTheme() {
-        const currentTheme = getPreferredTheme();
-        let newTheme;
-        if (currentTheme === 'system') {
-          newTheme = 'light';
-        } else if (currentTheme === 'light') {
-          newTheme = 'dark';
-        } else {
-          newTheme = 'system';
-        }
-        setTheme(newTheme);
-      }
-	</script>
-	<style>
-      [data-bs-theme="dark"] {
-        --bs-body-color: #e9ecef;
-        --bs-secondary-color: #adb5bd;
-        --bs-tertiary-color: #dee2e6;
-        --bs-link-color: #6ea8fe;
-        --bs-link-hover-color: #9ec5fe;
-      }
-      [data-bs-theme="dark"] .card-title {
-        color: #6ea8fe;
-      }
-      [data-bs-theme="dark"] .text-secondary {
-        color: #ced4da !important;
-      }
-      [data-bs-theme="dark"] .text-muted {
-        color: #adb5bd !important;
-      }
-      [data-bs-theme="dark"] code:not(pre code) {
-        background-color: var(--bs-tertiary-bg);
-        color: #e6edf3;
-      }
-      [data-bs-theme="dark"] blockquote {
-        background-color: var(--bs-tertiary-bg);
-        border-left-color: var(--bs-border-color);
-        color: var(--bs-secondary-color);
-      }
-      /* Mermaid DAG styling */
-      .mermaid {
-        overflow-x: auto;
-        max-width: 100%;
-      }
-      .mermaid svg {
-        max-width: 100% !important;
-      }
-      .mermaid .inverted rect {
-        fill: #333 !important;
-        stroke: #000 !important;
-      }
-      .mermaid .inverted .label, .mermaid .inverted span {
-        color: #fff !important;
-      }
-
-      /* Leaf nodes styling */
-      .mermaid .leaf rect {
-        fill: #28a745 !important;
-        stroke: #1e7e34 !important;
-      }
-      .mermaid .leaf .label, .mermaid .leaf span {
-        color: #fff !important;
-      }
-
-      [data-bs-theme="dark"] .mermaid .inverted rect {
-        fill: #eee !important;
-        stroke: #fff !important;
-      }
-      [data-bs-theme="dark"] .mermaid .inverted .label, [data-bs-theme="dark"] .mermaid .inverted span {
-        color: #111 !important;
-      }
-	      /* Zoom widget styling */
-      #mermaid-container {
-        border: 1px solid var(--bs-border-color);
-        border-radius: 4px;
-        background-color: var(--bs-body-bg);
-        position: relative;
-        height: 85vh; /* Increased slightly */
-        min-height: 600px;
-        width: 100%;
-        overflow: hidden;
-      }
-      #dag-mermaid {
-        width: 100%;
-        height: 100%;
-      }
-      #dag-mermaid {
-        width: 100%;
-        height: 100%;
-      }
-      #mermaid-zoom-controls {
-        position: absolute;
-        top: 10px;
-        left: 10px;
-        z-index: 100;
-        display: flex;
-        flex-direction: column;
-        gap: 5px;
-      }
-	</style>
-    <script src="https://cdn.jsdelivr.net/npm/svg-pan-zoom@3.6.1/dist/svg-pan-zoom.min.js"></script>
+{{template "head" .}}
+    {{if .Feed}}<link rel="alternate" type="application/atom+xml" title="Releases" href="feed.xml">{{end}}
+    {{.Site.Script .Root "panzoom.js"}}
+    {{.Site.Script .Root "search.js"}}
+    {{.Site.Script .Root "browse.js"}}
+    <script type="application/json" id="search-index">{{.SearchIndex}}</script>
 </head>
 <body>
     <div class="container">
 		<div class="d-flex justify-content-between align-items-center mt-5">
-			<h1 class="mb-0"><a href="https://www.hdlfactory.com">My</a> <a
-			href="https://bazel.build">Bazel</a> Registry</h1>
-			<button class="btn btn-outline-secondary" onclick="toggleTheme()" id="themeToggle" title="Toggle theme">
-				<i class="bi bi-circle-half"></i>
-			</button>
+			<h1 class="mb-0">{{.Site.Heading}}</h1>
+			{{template "themeToggle"}}
 		</div>
 
+		{{with .Site.Intro}}
+		{{.}}
+		{{else}}
 		<p>These modules are published in <a
-		href="https://github.com/filmil/bazel-registry">my private bazel
+		href="{{.Site.RepoURL}}">my private bazel
 		registry</a> See the <a
-		href="https://github.com/filmil/bazel-registry#usage">usage details</a>
+		href="{{.Site.RepoURL}}#usage">usage details</a>
 		for how to configure bazel use this additional registry. </p>
 
 		<p> The bazel central registry is still available at <a
 		href="https://bcr.bazel.build"> https://bcr.bazel.build</a>. </p>
-
-        <input class="form-control mb-4" id="searchInput" type="text" placeholder="Search for modules...">
+		{{end}}
+
+        {{with .WhatsNew}}
+        <h2>What's new</h2>
+        {{template "changelogEvents" .}}
+        <p><a href="changelog.html">Full changelog</a>{{if $.Feed}} &middot; <a href="feed.xml"><i class="bi bi-rss"></i> Subscribe</a>{{end}}</p>
+        {{end}}
+
+        <div class="d-flex gap-2 mb-2">
+            <input class="form-control" id="searchInput" type="search" placeholder="Search for modules{{with .Facets.SearchExample}}, e.g. {{.}}{{end}}" title="Terms match names, descriptions, maintainers, dependencies, versions and repositories. Restrict a term with name:, description:, maintainer:, dep:, version: or repo:.">
+            <select class="form-select w-auto" id="sortSelect" title="Sort modules">
+                <option value="name">By name</option>
+                <option value="updated">Recently updated</option>
+            </select>
+        </div>
+        <div class="d-flex flex-wrap gap-2 mb-4" id="filters">
+            {{with .Facets}}
+            <select class="form-select form-select-sm w-auto" id="tagFilter" title="Filter by tag">
+                <option value="">All tags</option>
+                {{range .Tags}}<option value="{{.Value}}">{{.Value}} ({{.Count}})</option>{{end}}
+            </select>
+            <select class="form-select form-select-sm w-auto" id="maintainerFilter" title="Filter by maintainer">
+                <option value="">All maintainers</option>
+                {{range .Maintainers}}<option value="{{.Value}}">{{.Value}} ({{.Count}})</option>{{end}}
+            </select>
+            <select class="form-select form-select-sm w-auto" id="hostFilter" title="Filter by the host of the source archive">
+                <option value="">All source hosts</option>
+                {{range .Hosts}}<option value="{{.Value}}">{{.Value}} ({{.Count}})</option>{{end}}
+            </select>
+            <select class="form-select form-select-sm w-auto" id="statusFilter" title="Filter by status">
+                <option value="">Any status</option>
+                {{range .Statuses}}<option value="{{.Value}}">{{.Value}} ({{.Count}})</option>{{end}}
+            </select>
+            <div class="form-check align-self-center">
+                <input class="form-check-input" type="checkbox" id="attestedFilter">
+                <label class="form-check-label" for="attestedFilter">Has attestations ({{.Attested}})</label>
+            </div>
+            {{end}}
+            <select class="form-select form-select-sm w-auto" id="groupSelect" title="Group modules">
+                <option value="">No grouping</option>
+                <option value="category">Group by category</option>
+                <option value="maintainer">Group by maintainer</option>
+                <option value="host">Group by source host</option>
+                <option value="status">Group by status</option>
+            </select>
+            <span class="small text-muted align-self-center" id="moduleCount"></span>
+        </div>
         <div class="row" id="module-cards">
             {{range $module := .Modules}}
-            <div class="col-md-4 mb-4 module-card" id="card-{{sanitizeID $module.Name}}">
+            <div class="col-md-4 mb-4 module-card{{if $module.Metadata.Deprecated}} deprecated{{end}}" id="card-{{sanitizeID $module.Name}}" data-updated="{{$module.LastUpdated.Unix}}">
                 <div class="card">
                     <div class="card-body">
                         <h5 class="card-title">
-							{{$module.Name}}
+							<span class="module-name">{{$module.Name}}</span>
 							<a href="{{$module.Metadata.Homepage}}"><i class="bi bi-link-45deg"></i></a>
+							{{if $.ModulePages}}<a href="{{$module.Name}}/index.html" title="Module details"><i class="bi bi-info-circle"></i></a>{{end}}
+							{{if $module.Metadata.Deprecated}}<span class="badge bg-secondary" style="font-size: 0.6em;">deprecated</span>{{end}}
+							{{if $module.Overlaid}}<span class="badge bg-warning text-dark" style="font-size: 0.6em;" title="Contains files from an overlay directory, not yet published.">overlay</span>{{end}}
 						</h5>
+                        {{with $module.Metadata.Description}}<p class="card-text module-description">{{.}}</p>{{end}}
+                        {{with $module.Metadata.Tags}}<p class="card-text mb-2">{{range .}}<a href="#" class="badge bg-info text-dark me-1" onclick="searchFor('tag:{{.}}'); return false;">{{.}}</a>{{end}}</p>{{end}}
+                        <p class="card-text small text-muted search-hits" style="display: none;"></p>
+                        {{if $module.Metadata.Deprecated}}
+                            <div class="alert alert-secondary py-1 px-2 mb-2" style="font-size: 0.9em;">
+                                {{$module.Metadata.Deprecated}}
+                                {{with $module.Metadata.ReplacedBy}}
+                                    Use <a href="#card-{{sanitizeID .}}">{{.}}</a> instead.
+                                {{end}}
+                            </div>
+                        {{end}}
                         <div class="card-text mb-2">
                             <strong>Versions:</strong>
                             {{if gt (len $module.Versions) 0}}
@@ -594,12 +769,17 @@ const htmlTemplate = `
                                     <span class="me-2"><del>{{$latest.Name}}</del></span>
                                 {{else}}
                                     <span class="me-2" data-bs-toggle="tooltip" data-bs-placement="top" title="{{ bazelDep $module.Name $latest.Name }}">
-                                        <a href="https://github.com/filmil/bazel-registry/tree/main/modules/{{$module.Name}}/{{$latest.Name}}">{{$latest.Name}}</a>
-                                        <a href="#" onclick="copyToClipboard('{{ bazelDep $module.Name $latest.Name }}'); return false;">
+                                        <a href="{{$.Site.BrowseURL}}/{{$module.Name}}/{{$latest.Name}}">{{$latest.Name}}</a>
+                                        {{if $latest.Attestations}}<span class="badge bg-success" style="font-size: 0.6em;" title="Attested: {{range $j, $a := $latest.Attestations.Artifacts}}{{if $j}}, {{end}}{{$a}}{{end}}"><i class="bi bi-patch-check"></i> provenance attested</span>{{end}}
+                                        {{if $latest.Overlaid}}<span class="badge bg-warning text-dark" style="font-size: 0.6em;" title="Comes from an overlay directory, not yet published.">overlay</span>{{end}}
+                                        <a href="#" onclick="copyToClipboard('{{ $.Site.Setup $module $latest }}'); return false;" title="Copy the setup">
                                             <i class="bi bi-clipboard"></i>
                                         </a>
                                     </span>
                                 {{end}}
+                                {{if not $latest.Published.IsZero}}
+                                    <small class="text-muted">published {{template "timeAgo" $latest.Published}}</small>
+                                {{end}}
 
                                 {{if gt (len $module.Versions) 1}}
                                     <details>
@@ -611,8 +791,10 @@ const htmlTemplate = `
                                                     <span class="me-2"><del>{{$v.Name}}</del></span>
                                                 {{else}}
                                                     <span class="me-2" data-bs-toggle="tooltip" data-bs-placement="top" title="{{ bazelDep $module.Name $v.Name }}">
-                                                        <a href="https://github.com/filmil/bazel-registry/tree/main/modules/{{$module.Name}}/{{$v.Name}}">{{$v.Name}}</a>
-                                                        <a href="#" onclick="copyToClipboard('{{ bazelDep $module.Name $v.Name }}'); return false;">
+                                                        <a href="{{$.Site.BrowseURL}}/{{$module.Name}}/{{$v.Name}}">{{$v.Name}}</a>
+                                                        {{if $v.Attestations}}<span class="badge bg-success" style="font-size: 0.6em;" title="Attested: {{range $j, $a := $v.Attestations.Artifacts}}{{if $j}}, {{end}}{{$a}}{{end}}"><i class="bi bi-patch-check"></i> provenance attested</span>{{end}}
+                                                        {{if $v.Overlaid}}<span class="badge bg-warning text-dark" style="font-size: 0.6em;" title="Comes from an overlay directory, not yet published.">overlay</span>{{end}}
+                                                        <a href="#" onclick="copyToClipboard('{{ $.Site.Setup $module $v }}'); return false;" title="Copy the setup">
                                                             <i class="bi bi-clipboard"></i>
                                                         </a>
                                                     </span>
@@ -658,100 +840,37 @@ const htmlTemplate = `
 
 		<div class="mt-5">
 			<h3>Module Dependency DAG (Latest Versions)</h3>
-			<div id="mermaid-container">
-				<div id="mermaid-zoom-controls">
+			<div id="dag-container">
+				<div id="dag-zoom-controls">
 					<button class="btn btn-sm btn-secondary" onclick="panZoom.zoomIn()"><i class="bi bi-plus-lg"></i></button>
 					<button class="btn btn-sm btn-secondary" onclick="panZoom.zoomOut()"><i class="bi bi-dash-lg"></i></button>
 					<button class="btn btn-sm btn-secondary" onclick="panZoom.reset()"><i class="bi bi-arrows-fullscreen"></i></button>
 				</div>
-				<div class="mermaid" id="dag-mermaid">
-					{{.Mermaid}}
-				</div>
+				{{.Graph}}
 			</div>
 		</div>
     </div>
-    <script src="https://cdn.jsdelivr.net/npm/bootstrap@5.3.0-alpha1/dist/js/bootstrap.bundle.min.js"></script>
-	<script type="module">
-		import mermaid from 'https://cdn.jsdelivr.net/npm/mermaid@10/dist/mermaid.esm.min.mjs';
-		mermaid.initialize({
-            startOnLoad: false,
-            flowchart: { useMaxWidth: false }
-        });
-
-        // Use a global variable to store the panZoom instance
-        window.panZoom = null;
-
-        async function initMermaid() {
-            const container = document.getElementById('dag-mermaid');
-            const { svg } = await mermaid.render('dag-svg', container.textContent);
-            container.innerHTML = svg;
-
-            const svgElement = container.querySelector('svg');
-            svgElement.removeAttribute('height');
-            svgElement.removeAttribute('width');
-            svgElement.style.width = '100%';
-            svgElement.style.height = '100%';
-            svgElement.style.maxWidth = '100%';
-
-            window.panZoom = svgPanZoom(svgElement, {
-                zoomEnabled: true,
-                controlIconsEnabled: true,
-                fit: true,
-                center: true,
-                minZoom: 0.01,
-                maxZoom: 50,
-                refreshRate: 'auto',
-            });
-
-            // Re-fit and center after rendering is complete
-            window.panZoom.resize();
-            window.panZoom.fit();
-            window.panZoom.center();
-
-            // Also add a resize listener
-            window.addEventListener('resize', () => {
-                window.panZoom.resize();
-                window.panZoom.fit();
-                window.panZoom.center();
-            });
-        }
-
-        document.addEventListener('DOMContentLoaded', () => {
-            initMermaid();
-        });
-	</script>
+    {{template "bootstrapScript" .}}
     <script>
-        const searchInput = document.getElementById('searchInput');
-        const moduleCards = document.querySelectorAll('.module-card');
-
-        searchInput.addEventListener('keyup', (event) => {
-            const filter = event.target.value.toLowerCase();
-            moduleCards.forEach(card => {
-                const title = card.querySelector('.card-title').textContent.toLowerCase();
-                if (title.includes(filter)) {
-                    card.style.display = '';
-                } else {
-                    card.style.display = 'none';
-                }
-            });
-        });
-
         const tooltipTriggerList = document.querySelectorAll('[data-bs-toggle="tooltip"]');
-        const tooltipList = [...tooltipTriggerList].map(tooltipTriggerEl => new bootstrap.Tooltip(tooltipTriggerEl));
-
-        function copyToClipboard(text) {
-            navigator.clipboard.writeText(text).then(function() {
-                /* clipboard successfully set */
-            }, function() {
-                /* clipboard write failed */
-                alert('Failed to copy');
-            });
+        if (window.bootstrap) {
+            const tooltipList = [...tooltipTriggerList].map(tooltipTriggerEl => new bootstrap.Tooltip(tooltipTriggerEl));
         }
     </script>
-    <footer class="text-center mt-4 py-3">
-        <p>&copy; 2025-present Filip Filmar. All rights reserved.</p>
-        <p><small>This page was generated by an automated coding assistant.</small></p>
-    </footer>
+    {{if .LiveReload}}
+    <script>
+        let registryStamp = null;
+        setInterval(async () => {
+            const response = await fetch('/_serve/stamp', { cache: 'no-store' });
+            const stamp = await response.text();
+            if (registryStamp !== null && stamp !== registryStamp) {
+                location.reload();
+            }
+            registryStamp = stamp;
+        }, 2000);
+    </script>
+    {{end}}
+    {{template "footer" .}}
 </body>
 </html>
 `
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ModulePageData is the data for the detail page of a module.
+type ModulePageData struct {
+	Title  string
+	Root   string
+	Site   SiteOptions
+	Module Module
+	// Dependents maps each version of the module to the module versions in
+	// the registry that depend on it, as "name@version".
+	Dependents map[string][]string
+}
+
+// modulePageLink is the link from the index page to the page of a module.
+func modulePageLink(name string) string {
+	return name + "/index.html"
+}
+
+// writeModulePages writes a detail page for each module into
+// outputDir/<module>/index.html, and for each of its versions into
+// outputDir/<module>/<version>/index.html. It fails if any of the declared
+// modules, see declaredModules, was skipped.
+func writeModulePages(tmpl *template.Template, modules []Module, declared []string, outputDir string, site SiteOptions) error {
+	if err := checkDeclared(modules, declared); err != nil {
+		return err
+	}
+	site = site.withDefaults()
+	dependents := reverseDependencies(modules)
+	for _, m := range modules {
+		data := ModulePageData{
+			Title:      m.Name,
+			Root:       "../",
+			Site:       site,
+			Module:     m,
+			Dependents: dependents[m.Name],
+		}
+		if err := writePage(outputDir, modulePageLink(m.Name), tmpl, "module", data); err != nil {
+			return fmt.Errorf("module %s: %w", m.Name, err)
+		}
+		for i, v := range m.Versions {
+			var previous *Version
+			// Versions are sorted newest first.
+			if i+1 < len(m.Versions) {
+				previous = &m.Versions[i+1]
+			}
+			data := newVersionPageData(m, v, previous, dependents[m.Name][v.Name])
+			data.Site = site
+			if err := writePage(outputDir, versionPageLink(m.Name, v.Name), tmpl, "version", data); err != nil {
+				return fmt.Errorf("module %s version %s: %w", m.Name, v.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// writePage renders the named template into the page at link, relative to
+// outputDir.
+func writePage(outputDir, link string, tmpl *template.Template, name string, data interface{}) error {
+	var buf bytes.Buffer
+	if err := executeTemplate(tmpl, name, data, &buf); err != nil {
+		return err
+	}
+	pagePath := filepath.Join(outputDir, filepath.FromSlash(link))
+	if err := os.MkdirAll(filepath.Dir(pagePath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(pagePath, buf.Bytes(), 0644)
+}
+
+// reverseDependencies maps module name and version to the module versions
+// in the registry that depend on that version.
+func reverseDependencies(modules []Module) map[string]map[string][]string {
+	result := make(map[string]map[string][]string)
+	for _, m := range modules {
+		for _, v := range m.Versions {
+			for _, dep := range v.Dependencies {
+				if result[dep.Name] == nil {
+					result[dep.Name] = make(map[string][]string)
+				}
+				result[dep.Name][dep.Version] = append(result[dep.Name][dep.Version], m.Name+"@"+v.Name)
+			}
+		}
+	}
+	for _, versions := range result {
+		for _, dependents := range versions {
+			sort.Strings(dependents)
+		}
+	}
+	return result
+}
+
+var presubmitListRe = regexp.MustCompile(`^\s+(\w+):\s*\[(.*)\]\s*$`)
+
+// presubmitMatrix extracts the build matrix from a presubmit.yml, e.g.
+// {"bazel": ["9.x"], "platform": ["debian11", "ubuntu2204"]}. Only the
+// inline list form of the matrix is understood.
+func presubmitMatrix(presubmit string) map[string][]string {
+	matrix := make(map[string][]string)
+	inMatrix := false
+	for _, line := range strings.Split(presubmit, "\n") {
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			inMatrix = strings.TrimSpace(line) == "matrix:"
+			continue
+		}
+		if !inMatrix {
+			continue
+		}
+		match := presubmitListRe.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		for _, value := range strings.Split(match[2], ",") {
+			value = strings.Trim(strings.TrimSpace(value), `"'`)
+			if value != "" {
+				matrix[match[1]] = append(matrix[match[1]], value)
+			}
+		}
+	}
+	return matrix
+}
+
+const moduleTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+{{template "head" .}}
+</head>
+<body>
+    {{$module := .Module}}
+    <div class="container">
+		<div class="d-flex justify-content-between align-items-center mt-5">
+			<h1 class="mb-0">
+				{{$module.Name}}
+				{{if $module.Metadata.Deprecated}}<span class="badge bg-secondary fs-6">deprecated</span>{{end}}
+				{{if $module.Overlaid}}<span class="badge bg-warning text-dark fs-6">overlay</span>{{end}}
+			</h1>
+			{{template "themeToggle"}}
+		</div>
+		<p class="mt-2"><a href="{{.Root}}index.html#card-{{sanitizeID $module.Name}}"><i class="bi bi-arrow-left"></i> All modules</a></p>
+		{{with $module.Metadata.Description}}<p class="lead">{{.}}</p>{{end}}
+		{{with $module.Metadata.Tags}}<p>{{range .}}<span class="badge bg-info text-dark me-1">{{.}}</span>{{end}}</p>{{end}}
+
+		{{if $module.Metadata.Deprecated}}
+			<div class="alert alert-secondary">
+				{{$module.Metadata.Deprecated}}
+				{{with $module.Metadata.ReplacedBy}}
+					Use <a href="{{$.Root}}{{.}}/index.html">{{.}}</a> instead.
+				{{end}}
+			</div>
+		{{end}}
+
+		<dl class="row">
+			<dt class="col-sm-2">Homepage</dt>
+			<dd class="col-sm-10"><a href="{{$module.Metadata.Homepage}}">{{$module.Metadata.Homepage}}</a></dd>
+			<dt class="col-sm-2">Repository</dt>
+			<dd class="col-sm-10">
+				{{range $repo := $module.Metadata.Repo}}<a href="{{repoURL $repo}}">{{$repo}}</a> {{end}}
+			</dd>
+			<dt class="col-sm-2">Maintainers</dt>
+			<dd class="col-sm-10">
+				{{range $i, $m := $module.Metadata.Maintainers}}{{if $i}}, {{end}}{{if $m.GitHub}}<a href="https://github.com/{{$m.GitHub}}">{{or $m.Name $m.GitHub}}</a>{{else}}{{$m.Name}}{{end}}{{end}}
+			</dd>
+			<dt class="col-sm-2">Versions</dt>
+			<dd class="col-sm-10">
+				{{range $v := $module.Versions}}
+					<a class="me-2" href="#version-{{sanitizeID $v.Name}}">{{if isYanked $v.Name $module.Metadata}}<del>{{$v.Name}}</del>{{else}}{{$v.Name}}{{end}}</a>
+				{{end}}
+			</dd>
+		</dl>
+
+		{{range $v := $module.Versions}}
+		<div class="card mb-4" id="version-{{sanitizeID $v.Name}}">
+			<div class="card-header d-flex justify-content-between align-items-center">
+				<h4 class="mb-0">
+					{{if isYanked $v.Name $module.Metadata}}<del>{{$v.Name}}</del>{{else}}{{$v.Name}}{{end}}
+					{{if $v.Attestations}}<span class="badge bg-success fs-6"><i class="bi bi-patch-check"></i> provenance attested</span>{{end}}
+					{{if $v.Overlaid}}<span class="badge bg-warning text-dark fs-6">overlay</span>{{end}}
+					{{if not $v.Published.IsZero}}<small class="text-muted fs-6">published {{template "timeAgo" $v.Published}}</small>{{end}}
+				</h4>
+				<span>
+					<code>{{bazelDep $module.Name $v.Name}}</code>
+					<a href="#" onclick="copyToClipboard('{{ $.Site.Setup $module $v }}'); return false;" title="Copy the setup"><i class="bi bi-clipboard"></i></a>
+					<a href="{{$v.Name}}/index.html" title="Version details"><i class="bi bi-file-earmark-diff"></i></a>
+					<a href="{{$.Site.BrowseURL}}/{{$module.Name}}/{{$v.Name}}" title="Browse the files"><i class="bi bi-github"></i></a>
+				</span>
+			</div>
+			<div class="card-body">
+				{{with index $module.Metadata.YankedVersions $v.Name}}
+					<div class="alert alert-danger">Yanked: {{.}}</div>
+				{{end}}
+				<div class="row">
+					<div class="col-md-6">
+						<h5>Dependencies</h5>
+						{{if $v.Dependencies}}
+						<ul class="list-unstyled ms-2">
+							{{range $dep := $v.Dependencies}}
+							<li>
+								<code>{{$dep.Name}}</code> ({{$dep.Version}})
+								{{if $dep.DevDependency}}<span class="badge bg-secondary" style="font-size: 0.6em;">dev</span>{{end}}
+							</li>
+							{{end}}
+						</ul>
+						{{else}}
+						<p class="text-muted">None.</p>
+						{{end}}
+					</div>
+					<div class="col-md-6">
+						<h5>Used by</h5>
+						{{with index $.Dependents $v.Name}}
+						<ul class="list-unstyled ms-2">
+							{{range $d := .}}<li><code>{{$d}}</code></li>{{end}}
+						</ul>
+						{{else}}
+						<p class="text-muted">No module in this registry.</p>
+						{{end}}
+					</div>
+				</div>
+
+				{{with $v.Presubmit}}
+				<h5>Presubmit</h5>
+				{{with presubmitMatrix .}}
+				<table class="table table-sm w-auto">
+					{{range $key, $values := .}}
+					<tr><th>{{$key}}</th><td>{{range $values}}<span class="badge bg-info text-dark me-1">{{.}}</span>{{end}}</td></tr>
+					{{end}}
+				</table>
+				{{end}}
+				<details class="mb-3">
+					<summary>presubmit.yml</summary>
+					<pre class="bg-body-tertiary p-2"><code>{{.}}</code></pre>
+				</details>
+				{{end}}
+
+				<details class="mb-3">
+					<summary><strong>MODULE.bazel</strong></summary>
+					<pre class="bg-body-tertiary p-2"><code>{{highlightStarlark $v.ModuleFile}}</code></pre>
+				</details>
+				<details class="mb-3">
+					<summary><strong>source.json</strong></summary>
+					<pre class="bg-body-tertiary p-2"><code>{{$v.SourceFile}}</code></pre>
+				</details>
+				{{range $p := $v.Patches}}
+				<details class="mb-3">
+					<summary><strong>patches/{{$p.Name}}</strong></summary>
+					<pre class="bg-body-tertiary p-2"><code>{{highlightDiff $p.Content}}</code></pre>
+				</details>
+				{{end}}
+			</div>
+		</div>
+		{{end}}
+    </div>
+    {{template "bootstrapScript" .}}
+    {{template "footer" .}}
+</body>
+</html>
+`
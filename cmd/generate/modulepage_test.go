@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteModulePages(t *testing.T) {
+	modulesDir := t.TempDir()
+	writeTestModule(t, modulesDir, "lib", map[string]string{
+		"1.0.0": `module(name = "lib", version = "1.0.0")`,
+		"1.1.0": `module(name = "lib", version = "1.1.0")`,
+	})
+	writeTestModule(t, modulesDir, "my-app", map[string]string{
+		"2.0.0": `module(name = "my-app", version = "2.0.0")` + "\n" + `bazel_dep(name = "lib", version = "1.0.0")`,
+	})
+	writeTestFile(t, filepath.Join(modulesDir, "lib", "1.0.0", "patches", "fix.patch"), "+fixed <line>\n")
+	writeTestFile(t, filepath.Join(modulesDir, "lib", "1.0.0", "presubmit.yml"), `matrix:
+  platform: ["debian11", "ubuntu2204"]
+  bazel: [7.x, 8.x]
+tasks:
+  verify_targets:
+    platform: ${{ platform }}
+`)
+	modules, err := findModules(modulesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputDir := t.TempDir()
+	if err := writeModulePages(testTemplates(t, ""), modules, nil, outputDir, SiteOptions{}); err != nil {
+		t.Fatalf("writeModulePages failed: %v", err)
+	}
+	page, err := os.ReadFile(filepath.Join(outputDir, "lib", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<title>lib - Bazel Registry</title>`,
+		`href="../hdlfactory.png"`,
+		`id="version-1_0_0"`,
+		`<code>my-app@2.0.0</code>`,
+		`patches/fix.patch`,
+		`fixed &lt;line&gt;`,
+		`ubuntu2204</span>`,
+	} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("expected the module page to contain %q", want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "my-app", "index.html")); err != nil {
+		t.Errorf("expected a page for my-app: %v", err)
+	}
+	versionPage, err := os.ReadFile(filepath.Join(outputDir, "lib", "1.1.0", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `Changes since <a href="../1.0.0/index.html">1.0.0</a>`; !strings.Contains(string(versionPage), want) {
+		t.Errorf("expected the version page to contain %q", want)
+	}
+
+	mermaid := buildMermaidWithLinks(modules, modulePageLink, true)
+	if !strings.Contains(mermaid, `click my_app "my-app/index.html"`) {
+		t.Errorf("expected graph clicks to go to module pages, got: %s", mermaid)
+	}
+}
+
+func TestPresubmitMatrix(t *testing.T) {
+	got := presubmitMatrix("matrix:\n  platform: [\"debian11\", 'macos']\n  bazel: [\"9.x\"]\ntasks:\n  t:\n    platform: [\"x\"]\n")
+	want := map[string][]string{"platform": {"debian11", "macos"}, "bazel": {"9.x"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("presubmitMatrix() = %v, want %v", got, want)
+	}
+}
+
+func TestWriteModulePagesSkippedModule(t *testing.T) {
+	modulesDir := t.TempDir()
+	writeTestModule(t, modulesDir, "lib", map[string]string{"1.0.0": `module(name = "lib", version = "1.0.0")`})
+	writeTestFile(t, filepath.Join(modulesDir, "broken", "metadata.json"), "{")
+	modules, err := findModules(modulesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	declared, err := declaredModules(os.DirFS(modulesDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"broken", "lib"}; !reflect.DeepEqual(declared, want) {
+		t.Errorf("declaredModules() = %v, want %v", declared, want)
+	}
+	err = writeModulePages(testTemplates(t, ""), modules, declared, t.TempDir(), SiteOptions{})
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("expected an error for the skipped module, got: %v", err)
+	}
+}
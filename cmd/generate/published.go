@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// parsePublishedAt parses a publication date from metadata.json, either an
+// RFC 3339 timestamp or a plain date.
+func parsePublishedAt(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("malformed publication date %q, want YYYY-MM-DD or an RFC 3339 timestamp", s)
+}
+
+// publishedTimes maps "module@version" to the time the version was
+// published, from the changelog.
+func publishedTimes(events []ChangeEvent) map[string]time.Time {
+	result := make(map[string]time.Time)
+	for _, e := range events {
+		if e.Kind == eventVersion {
+			result[e.Module+"@"+e.Version] = e.Time
+		}
+	}
+	return result
+}
+
+// setPublished sets the publication times of the versions that do not have
+// one in metadata.json from the changelog.
+func setPublished(modules []Module, events []ChangeEvent) {
+	published := publishedTimes(events)
+	for i := range modules {
+		m := &modules[i]
+		for j := range m.Versions {
+			v := &m.Versions[j]
+			if !v.Published.IsZero() {
+				continue
+			}
+			if t, ok := published[m.Name+"@"+v.Name]; ok {
+				v.Published = t
+			}
+		}
+	}
+}
+
+// LastUpdated returns when the newest version of the module was published,
+// or zero if no publication time is known.
+func (m Module) LastUpdated() time.Time {
+	var last time.Time
+	for _, v := range m.Versions {
+		if v.Published.After(last) {
+			last = v.Published
+		}
+	}
+	return last
+}
+
+// moduleSummary is a module in the JSON output mode.
+type moduleSummary struct {
+	Name        string           `json:"name"`
+	Deprecated  bool             `json:"deprecated,omitempty"`
+	LastUpdated *time.Time       `json:"last_updated,omitempty"`
+	Versions    []versionSummary `json:"versions"`
+}
+
+type versionSummary struct {
+	Version   string     `json:"version"`
+	Published *time.Time `json:"published,omitempty"`
+	Yanked    bool       `json:"yanked,omitempty"`
+}
+
+// writeModulesJSON writes the modules, their versions and publication
+// times as JSON.
+func writeModulesJSON(modules []Module, w io.Writer) error {
+	optionalTime := func(t time.Time) *time.Time {
+		if t.IsZero() {
+			return nil
+		}
+		return &t
+	}
+	summaries := []moduleSummary{}
+	for _, m := range modules {
+		summary := moduleSummary{
+			Name:        m.Name,
+			Deprecated:  m.Metadata.Deprecated != "",
+			LastUpdated: optionalTime(m.LastUpdated()),
+			Versions:    []versionSummary{},
+		}
+		for _, v := range m.Versions {
+			_, yanked := m.Metadata.YankedVersions[v.Name]
+			summary.Versions = append(summary.Versions, versionSummary{
+				Version:   v.Name,
+				Published: optionalTime(v.Published),
+				Yanked:    yanked,
+			})
+		}
+		summaries = append(summaries, summary)
+	}
+	content, err := marshalJSON(summaries)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(content)
+	return err
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPublished(t *testing.T) {
+	modulesDir := t.TempDir()
+	writeTestModule(t, modulesDir, "lib", map[string]string{
+		"1.0.0": `module(name = "lib", version = "1.0.0")`,
+		"1.1.0": `module(name = "lib", version = "1.1.0")`,
+	})
+	metadata, err := marshalJSON(Metadata{
+		Homepage:       "https://example.com/lib",
+		Maintainers:    []Maintainer{{GitHub: "example"}},
+		Repo:           []string{"github:example/lib"},
+		Versions:       []string{"1.0.0", "1.1.0"},
+		YankedVersions: map[string]string{},
+		PublishedAt:    map[string]string{"1.0.0": "2024-12-24"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(modulesDir, "lib", "metadata.json"), string(metadata))
+	modules, err := findModules(modulesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setPublished(modules, []ChangeEvent{
+		{Time: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), Kind: eventVersion, Module: "lib", Version: "1.1.0"},
+		{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Kind: eventVersion, Module: "lib", Version: "1.0.0"},
+	})
+	lib := modules[0]
+	// Versions are sorted newest first.
+	if got := lib.Versions[1].Published; !got.Equal(time.Date(2024, 12, 24, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected published_at to override the changelog, got %v", got)
+	}
+	if got := lib.LastUpdated(); !got.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("LastUpdated() = %v", got)
+	}
+
+	var sb strings.Builder
+	if err := writeModulesJSON(modules, &sb); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"last_updated": "2025-03-01T00:00:00Z"`,
+		`"version": "1.0.0",
+                "published": "2024-12-24T00:00:00Z"`,
+	} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("expected the JSON to contain %q, got:\n%s", want, sb.String())
+		}
+	}
+
+	outputDir := t.TempDir()
+	if err := writeModulePages(testTemplates(t, ""), modules, nil, outputDir, SiteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	page, err := os.ReadFile(filepath.Join(outputDir, "lib", "1.1.0", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The age is filled in by registry.js.
+	if want := `<time class="time-ago" datetime="2025-03-01T00:00:00Z" title="2025-03-01">2025-03-01</time>`; !strings.Contains(string(page), want) {
+		t.Errorf("expected the version page to contain %q", want)
+	}
+}
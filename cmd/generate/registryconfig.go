@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// RegistryConfig is the content of bazel_registry.json at the registry root.
+type RegistryConfig struct {
+	// Mirrors are tried by Bazel, in order, before the URLs of source
+	// archives.
+	Mirrors []string `json:"mirrors"`
+	// ModuleBasePath is where local_path sources are looked up, relative
+	// to the registry root.
+	ModuleBasePath string `json:"module_base_path,omitempty"`
+}
+
+func runRegistryConfig(args []string) error {
+	var (
+		registryDir    string
+		moduleBasePath string
+		mirrors        stringsFlag
+		check          bool
+	)
+	fs := flag.NewFlagSet("registry-config", flag.ExitOnError)
+	fs.StringVar(&registryDir, "registry_dir", ".", "The registry root, which contains the modules directory.")
+	fs.Var(&mirrors, "mirror", "A mirror URL to list in bazel_registry.json. May be repeated.")
+	fs.StringVar(&moduleBasePath, "module_base_path", "modules", "The module base path to list in bazel_registry.json.")
+	fs.BoolVar(&check, "check", false, "Only validate the existing bazel_registry.json.")
+	fs.Parse(args)
+
+	configPath := filepath.Join(registryDir, registryConfigFile)
+	if check {
+		config, err := loadRegistryConfig(registryDir)
+		if err != nil {
+			return err
+		}
+		if err := validateRegistryConfig(config, registryDir); err != nil {
+			return fmt.Errorf("%s is not valid:\n%w", configPath, err)
+		}
+		return nil
+	}
+
+	config := RegistryConfig{
+		Mirrors:        []string(mirrors),
+		ModuleBasePath: moduleBasePath,
+	}
+	if config.Mirrors == nil {
+		config.Mirrors = []string{}
+	}
+	if err := validateRegistryConfig(config, registryDir); err != nil {
+		return err
+	}
+	content, err := marshalJSON(config)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(configPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", configPath, err)
+	}
+	log.Printf("wrote %s", configPath)
+	return nil
+}
+
+// loadRegistryConfig reads bazel_registry.json from the registry root. A
+// missing file is the same as an empty configuration.
+func loadRegistryConfig(registryDir string) (RegistryConfig, error) {
+	var config RegistryConfig
+	content, err := os.ReadFile(filepath.Join(registryDir, registryConfigFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return config, fmt.Errorf("failed to parse %s: %w", registryConfigFile, err)
+	}
+	return config, nil
+}
+
+// validateRegistryConfig checks that the mirrors are usable URL prefixes,
+// and that the module base path is a directory inside the registry.
+func validateRegistryConfig(config RegistryConfig, registryDir string) error {
+	var errs []error
+	seen := make(map[string]bool)
+	for _, mirror := range config.Mirrors {
+		u, err := url.Parse(mirror)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("invalid mirror %q: %w", mirror, err))
+		case u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "file":
+			errs = append(errs, fmt.Errorf("mirror %q: unsupported scheme %q", mirror, u.Scheme))
+		case u.Scheme != "file" && u.Host == "":
+			errs = append(errs, fmt.Errorf("mirror %q has no host", mirror))
+		case u.RawQuery != "" || u.Fragment != "":
+			errs = append(errs, fmt.Errorf("mirror %q can not have a query or a fragment", mirror))
+		}
+		if seen[mirror] {
+			errs = append(errs, fmt.Errorf("mirror %q is listed twice", mirror))
+		}
+		seen[mirror] = true
+	}
+	if p := config.ModuleBasePath; p != "" {
+		clean := path.Clean(p)
+		if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+			errs = append(errs, fmt.Errorf("module_base_path %q must be inside the registry", p))
+		} else if info, err := os.Stat(filepath.Join(registryDir, clean)); err != nil || !info.IsDir() {
+			errs = append(errs, fmt.Errorf("module_base_path %q is not a directory", p))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// mirrorURL returns the URL at which a mirror serves sourceURL. This is the
+// same scheme that Bazel uses: the mirror, followed by the host and path of
+// the original URL.
+func mirrorURL(mirror, sourceURL string) (string, error) {
+	u, err := url.Parse(sourceURL)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(mirror, "/") + "/" + u.Host + u.Path, nil
+}
+
+// rewriteSourceURLs points the url of a source.json at the first mirror.
+// The remaining mirrors and the original URL are kept as fallbacks in
+// mirror_urls.
+func rewriteSourceURLs(content []byte, mirrors []string) ([]byte, error) {
+	if len(mirrors) == 0 {
+		return content, nil
+	}
+	var source jsonObject
+	if err := json.Unmarshal(content, &source); err != nil {
+		return nil, err
+	}
+	var original string
+	if err := source.Get("url", &original); err != nil {
+		return nil, err
+	}
+	if original == "" {
+		return content, nil
+	}
+	var urls []string
+	for _, mirror := range mirrors {
+		u, err := mirrorURL(mirror, original)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, u)
+	}
+	var mirrorURLs []string
+	if err := source.Get("mirror_urls", &mirrorURLs); err != nil {
+		return nil, err
+	}
+	if err := source.Set("url", urls[0]); err != nil {
+		return nil, err
+	}
+	if err := source.Set("mirror_urls", append(append(urls[1:], original), mirrorURLs...)); err != nil {
+		return nil, err
+	}
+	source.Reorder(sourceKeyOrder)
+	return marshalJSON(&source)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidateRegistryConfig(t *testing.T) {
+	registryDir := t.TempDir()
+	writeTestFile(t, filepath.Join(registryDir, "modules", "README"), "")
+
+	valid := RegistryConfig{
+		Mirrors:        []string{"https://mirror.example.com/", "file:///srv/mirror"},
+		ModuleBasePath: "modules",
+	}
+	if err := validateRegistryConfig(valid, registryDir); err != nil {
+		t.Errorf("expected a valid config, got: %v", err)
+	}
+	for _, config := range []RegistryConfig{
+		{Mirrors: []string{"ftp://mirror.example.com/"}},
+		{Mirrors: []string{"https:///path"}},
+		{Mirrors: []string{"https://mirror.example.com/?x=1"}},
+		{Mirrors: []string{"https://a/", "https://a/"}},
+		{ModuleBasePath: "../elsewhere"},
+		{ModuleBasePath: "missing"},
+	} {
+		if err := validateRegistryConfig(config, registryDir); err == nil {
+			t.Errorf("expected an error for %+v", config)
+		}
+	}
+}
+
+func TestRewriteSourceURLs(t *testing.T) {
+	in := []byte(`{"integrity": "i", "url": "https://github.com/o/r/archive/v1.tar.gz"}`)
+	out, err := rewriteSourceURLs(in, []string{"https://mirror.example.com", "https://backup.example.com/m/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Source
+	var mirrors struct {
+		MirrorURLs []string `json:"mirror_urls"`
+	}
+	json.Unmarshal(out, &got)
+	json.Unmarshal(out, &mirrors)
+	if got.URL != "https://mirror.example.com/github.com/o/r/archive/v1.tar.gz" || got.Integrity != "i" {
+		t.Errorf("unexpected source: %+v", got)
+	}
+	want := []string{
+		"https://backup.example.com/m/github.com/o/r/archive/v1.tar.gz",
+		"https://github.com/o/r/archive/v1.tar.gz",
+	}
+	if !reflect.DeepEqual(mirrors.MirrorURLs, want) {
+		t.Errorf("mirror_urls = %v, want %v", mirrors.MirrorURLs, want)
+	}
+}
+
+func TestRegistryHandler_RewriteSourceURLs(t *testing.T) {
+	registryDir := t.TempDir()
+	modulesDir := filepath.Join(registryDir, "modules")
+	writeTestModule(t, modulesDir, "mod", map[string]string{"1.0.0": `module(name = "mod", version = "1.0.0")`})
+	writeTestFile(t, filepath.Join(registryDir, registryConfigFile), `{"mirrors": ["https://mirror.example.com/"]}`)
+
+	rec := httptest.NewRecorder()
+	newRegistryHandler(modulesDir, nil, true).ServeHTTP(rec, httptest.NewRequest("GET", "/modules/mod/1.0.0/source.json", nil))
+	body, _ := io.ReadAll(rec.Body)
+	var source Source
+	if err := json.Unmarshal(body, &source); err != nil {
+		t.Fatalf("unexpected response %d: %s", rec.Code, body)
+	}
+	if source.URL != "https://mirror.example.com/example.com/mod-1.0.0.tar.gz" {
+		t.Errorf("unexpected url: %s", source.URL)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+// overlayFS merges module trees. The first layer is the registry's modules
+// directory, and each following layer is an overlay directory whose files
+// add to, or replace, the files in the layers before it.
+type overlayFS []fs.FS
+
+// newRegistryFS returns the modules directory with the overlay directories
+// merged over it.
+func newRegistryFS(modulesDir string, overlayDirs []string) overlayFS {
+	layers := overlayFS{os.DirFS(modulesDir)}
+	for _, dir := range overlayDirs {
+		layers = append(layers, os.DirFS(dir))
+	}
+	return layers
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	for i := len(o) - 1; i >= 0; i-- {
+		f, err := o[i].Open(name)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir lists the union of the directory's entries in all layers.
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries := make(map[string]fs.DirEntry)
+	found := false
+	for _, layer := range o {
+		layerEntries, err := fs.ReadDir(layer, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		for _, e := range layerEntries {
+			entries[e.Name()] = e
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	var result []fs.DirEntry
+	for _, e := range entries {
+		result = append(result, e)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name() < result[j].Name()
+	})
+	return result, nil
+}
+
+// Overlaid reports whether name, or anything beneath it, comes from an
+// overlay directory rather than from the modules directory.
+func (o overlayFS) Overlaid(name string) bool {
+	for _, layer := range o[1:] {
+		if _, err := fs.Stat(layer, name); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOverlayFS(t *testing.T) {
+	dir := t.TempDir()
+	modulesDir := filepath.Join(dir, "modules")
+	overlayDir := filepath.Join(dir, "overlay")
+	writeTestModule(t, modulesDir, "mod", map[string]string{"1.0.0": `module(name = "mod", version = "1.0.0")`})
+	writeTestModule(t, modulesDir, "other", map[string]string{"1.0.0": `module(name = "other", version = "1.0.0")`})
+	// The overlay adds a version to mod, and replaces a file of mod@1.0.0.
+	writeTestFile(t, filepath.Join(overlayDir, "mod", "1.1.0", "MODULE.bazel"), `module(name = "mod", version = "1.1.0")`)
+	writeTestFile(t, filepath.Join(overlayDir, "mod", "1.1.0", "source.json"), testSource("mod", "1.1.0"))
+	writeTestFile(t, filepath.Join(overlayDir, "mod", "1.0.0", "MODULE.bazel"), `module(name = "mod", version = "1.0.0")`+"\n# replaced")
+
+	fsys := newRegistryFS(modulesDir, []string{overlayDir})
+	modules, err := findRegistryModules(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modules) != 2 {
+		t.Fatalf("expected 2 modules, got %d", len(modules))
+	}
+	mod, other := modules[0], modules[1]
+	if !mod.Overlaid || other.Overlaid {
+		t.Errorf("unexpected overlaid modules: mod=%v, other=%v", mod.Overlaid, other.Overlaid)
+	}
+	if len(mod.Versions) != 2 || mod.Versions[0].Name != "1.1.0" || !mod.Versions[0].Overlaid {
+		t.Fatalf("expected overlaid version 1.1.0, got: %+v", mod.Versions)
+	}
+	if !strings.HasSuffix(mod.Versions[1].ModuleFile, "# replaced") {
+		t.Errorf("expected MODULE.bazel from the overlay, got: %s", mod.Versions[1].ModuleFile)
+	}
+	if err := validateModule(mod); err != nil {
+		t.Errorf("expected overlaid versions to be listed, got: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := generateHTML(modules, "", &dummyWriteCloser{Buffer: &buf}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(buf.String(), ">overlay</span>"); got != 3 {
+		t.Errorf("expected the module and both its versions marked as overlaid, got %d marks", got)
+	}
+
+	req := httptest.NewRequest("GET", "/modules/mod/1.1.0/MODULE.bazel", nil)
+	rec := httptest.NewRecorder()
+	newRegistryHandler(modulesDir, []string{overlayDir}, false).ServeHTTP(rec, req)
+	if body, _ := io.ReadAll(rec.Body); string(body) != `module(name = "mod", version = "1.1.0")` {
+		t.Errorf("expected the overlaid MODULE.bazel to be served, got %d: %s", rec.Code, body)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// searchEntry is a module in the search index of the index page. The page
+// matches queries against it, see assets/search.js.
+type searchEntry struct {
+	// ID is the sanitized module name, as in the ID of its card.
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	// Maintainers holds the names, GitHub handles and emails of the
+	// maintainers.
+	Maintainers []string `json:"maintainers,omitempty"`
+	// MaintainerKeys identify the maintainers in the filters, see
+	// maintainerKey.
+	MaintainerKeys []string `json:"maintainer_keys,omitempty"`
+	// Deps are the dependencies of the latest version.
+	Deps     []string `json:"deps,omitempty"`
+	Versions []string `json:"versions,omitempty"`
+	// Repos holds the homepage and the repository URLs.
+	Repos []string `json:"repos,omitempty"`
+	// The facets of the module, see facets.go.
+	Host     string `json:"host,omitempty"`
+	Status   string `json:"status"`
+	Attested bool   `json:"attested,omitempty"`
+}
+
+// buildSearchIndex builds the search index of the modules.
+func buildSearchIndex(modules []Module) []searchEntry {
+	index := []searchEntry{}
+	for _, m := range modules {
+		entry := searchEntry{
+			ID:          sanitizeID(m.Name),
+			Name:        m.Name,
+			Description: m.Metadata.Description,
+			Tags:        m.Metadata.Tags,
+			Host:        m.SourceHost(),
+			Status:      m.Status(),
+			Attested:    m.Attested(),
+		}
+		for _, maintainer := range m.Metadata.Maintainers {
+			if key := maintainerKey(maintainer); key != "" {
+				entry.MaintainerKeys = append(entry.MaintainerKeys, key)
+			}
+			for _, s := range []string{maintainer.Name, maintainer.GitHub, maintainer.Email} {
+				if s != "" {
+					entry.Maintainers = append(entry.Maintainers, s)
+				}
+			}
+		}
+		if len(m.Versions) > 0 {
+			deps := make(map[string]bool)
+			for _, dep := range m.Versions[0].Dependencies {
+				deps[dep.Name] = true
+			}
+			for name := range deps {
+				entry.Deps = append(entry.Deps, name)
+			}
+			sort.Strings(entry.Deps)
+		}
+		for _, v := range m.Versions {
+			entry.Versions = append(entry.Versions, v.Name)
+		}
+		if m.Metadata.Homepage != "" {
+			entry.Repos = append(entry.Repos, m.Metadata.Homepage)
+		}
+		for _, repo := range m.Metadata.Repo {
+			if url := repoURL(repo); !strings.EqualFold(url, m.Metadata.Homepage) {
+				entry.Repos = append(entry.Repos, url)
+			}
+		}
+		index = append(index, entry)
+	}
+	return index
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestBuildSearchIndex(t *testing.T) {
+	modules := []Module{
+		{
+			Name: "rules-x",
+			Metadata: Metadata{
+				Homepage:    "https://github.com/o/rules-x",
+				Description: "Rules for X.",
+				Maintainers: []Maintainer{{Name: "Jane Doe", GitHub: "jdoe"}, {Email: "x@example.com"}},
+				Repo:        []string{"github:o/rules-x", "https://gitlab.com/o/rules-x"},
+			},
+			Versions: []Version{
+				{Name: "1.1.0", Dependencies: []Dependency{
+					{Name: "rules_go", Version: "0.50.0"},
+					{Name: "bazel_skylib", Version: "1.7.0", DevDependency: true},
+				}},
+				{Name: "1.0.0", Dependencies: []Dependency{{Name: "rules_cc", Version: "0.1"}}},
+			},
+		},
+		{Name: "empty"},
+	}
+	want := []searchEntry{
+		{
+			ID:             "rules_x",
+			Name:           "rules-x",
+			Description:    "Rules for X.",
+			Maintainers:    []string{"Jane Doe", "jdoe", "x@example.com"},
+			MaintainerKeys: []string{"jdoe", "x@example.com"},
+			Deps:           []string{"bazel_skylib", "rules_go"},
+			Versions:       []string{"1.1.0", "1.0.0"},
+			Repos:          []string{"https://github.com/o/rules-x", "https://gitlab.com/o/rules-x"},
+			Status:         statusActive,
+		},
+		{ID: "empty", Name: "empty", Status: statusActive},
+	}
+	if got := buildSearchIndex(modules); !reflect.DeepEqual(got, want) {
+		t.Errorf("buildSearchIndex() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSearchIndexInPage(t *testing.T) {
+	index := []searchEntry{{ID: "a", Name: "a", Description: `</script><b>"x"</b>`}}
+	var buf bytes.Buffer
+	if err := writeHTML(testTemplates(t, ""), TemplateData{SearchIndex: index}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	m := regexp.MustCompile(`(?s)<script type="application/json" id="search-index">(.*?)</script>`).FindStringSubmatch(buf.String())
+	if m == nil {
+		t.Fatalf("expected the page to embed the search index")
+	}
+	var got []searchEntry
+	if err := json.Unmarshal([]byte(m[1]), &got); err != nil {
+		t.Fatalf("failed to parse the embedded search index %q: %v", m[1], err)
+	}
+	if !reflect.DeepEqual(got, index) {
+		t.Errorf("embedded search index = %+v, want %+v", got, index)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const registryConfigFile = "bazel_registry.json"
+
+func runServe(args []string) error {
+	var (
+		modulesDir  string
+		addr        string
+		overlayDirs stringsFlag
+		rewriteURLs bool
+	)
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.StringVar(&modulesDir, "modules_dir", "modules", "The path to the modules directory.")
+	fs.StringVar(&addr, "addr", ":8080", "The address to serve the registry on.")
+	fs.Var(&overlayDirs, "overlay_dir", "A directory of modules to merge over the modules directory. May be repeated.")
+	fs.BoolVar(&rewriteURLs, "rewrite_source_urls", false, "Point the source.json urls at the mirrors listed in bazel_registry.json.")
+	fs.Parse(args)
+
+	registryDir := filepath.Dir(modulesDir)
+	config, err := loadRegistryConfig(registryDir)
+	if err != nil {
+		return err
+	}
+	if err := validateRegistryConfig(config, registryDir); err != nil {
+		return fmt.Errorf("%s is not valid:\n%w", registryConfigFile, err)
+	}
+	if rewriteURLs && len(config.Mirrors) == 0 {
+		log.Printf("warning: --rewrite_source_urls is set, but %s lists no mirrors", registryConfigFile)
+	}
+
+	url, err := serveURL(addr)
+	if err != nil {
+		return fmt.Errorf("invalid --addr: %w", err)
+	}
+	log.Printf("serving registry %s on %s", modulesDir, addr)
+	log.Printf("use it with: --registry=%s", url)
+	return http.ListenAndServe(addr, newRegistryHandler(modulesDir, overlayDirs, rewriteURLs))
+}
+
+// serveURL returns the URL that Bazel can reach a registry served on addr
+// at. An empty or unspecified host is reached through localhost.
+func serveURL(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port), nil
+}
+
+// newRegistryHandler serves the registry in modulesDir, with the overlay
+// directories merged over it, using the layout that Bazel expects, plus the
+// index page at the root. Everything is read from disk on each request, so
+// changes show up without a restart. If rewriteURLs is set, source.json
+// files are served with their urls pointing at the registry's mirrors.
+func newRegistryHandler(modulesDir string, overlayDirs []string, rewriteURLs bool) http.Handler {
+	fsys := newRegistryFS(modulesDir, overlayDirs)
+	tmpl, tmplErr := parseTemplates("")
+	mux := http.NewServeMux()
+	registryDir := filepath.Dir(modulesDir)
+	mux.HandleFunc("/"+registryConfigFile, func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, filepath.Join(registryDir, registryConfigFile))
+	})
+	files := http.StripPrefix("/modules/", http.FileServer(http.FS(fsys)))
+	mux.HandleFunc("/modules/", func(w http.ResponseWriter, r *http.Request) {
+		if !rewriteURLs || path.Base(r.URL.Path) != "source.json" {
+			files.ServeHTTP(w, r)
+			return
+		}
+		content, err := fs.ReadFile(fsys, strings.TrimPrefix(path.Clean(r.URL.Path), "/modules/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		config, err := loadRegistryConfig(registryDir)
+		if err == nil {
+			content, err = rewriteSourceURLs(content, config.Mirrors)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(content)
+	})
+	mux.HandleFunc("/_serve/stamp", func(w http.ResponseWriter, r *http.Request) {
+		stamp, err := registryStamp(fsys)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, stamp)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" && r.URL.Path != "/index.html" {
+			http.NotFound(w, r)
+			return
+		}
+		modules, err := findRegistryModules(fsys)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if tmplErr != nil {
+			http.Error(w, tmplErr.Error(), http.StatusInternalServerError)
+			return
+		}
+		var buf bytes.Buffer
+		err = writeHTML(tmpl, TemplateData{
+			Site:        SiteOptions{Branding: Branding{RegistryURL: "http://" + r.Host}},
+			Modules:     modules,
+			Graph:       buildGraph(modules, cardLink, false).SVG(),
+			SearchIndex: buildSearchIndex(modules),
+			Facets:      buildFacets(modules),
+			LiveReload:  true,
+		}, &buf)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(buf.Bytes())
+	})
+	return mux
+}
+
+// registryStamp summarizes the state of the files in the registry, so that
+// clients can tell when something changed.
+func registryStamp(fsys fs.FS) (string, error) {
+	var count int
+	var latest int64
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		count++
+		if t := info.ModTime().UnixNano(); t > latest {
+			latest = t
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%d", count, latest), nil
+}
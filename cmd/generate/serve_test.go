@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegistryHandler(t *testing.T) {
+	registryDir := t.TempDir()
+	modulesDir := filepath.Join(registryDir, "modules")
+	writeTestModule(t, modulesDir, "mod", map[string]string{"1.0.0": `module(name = "mod", version = "1.0.0")`})
+	writeTestFile(t, filepath.Join(modulesDir, "mod", "1.0.0", "patches", "fix.patch"), "--- a/x\n")
+	writeTestFile(t, filepath.Join(registryDir, registryConfigFile), `{"mirrors": []}`)
+
+	server := httptest.NewServer(newRegistryHandler(modulesDir, nil, false))
+	defer server.Close()
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	for path, want := range map[string]string{
+		"/bazel_registry.json":                 `{"mirrors": []}`,
+		"/modules/mod/1.0.0/MODULE.bazel":      `module(name = "mod", version = "1.0.0")`,
+		"/modules/mod/1.0.0/source.json":       testSource("mod", "1.0.0"),
+		"/modules/mod/1.0.0/patches/fix.patch": "--- a/x\n",
+	} {
+		if code, body := get(path); code != http.StatusOK || body != want {
+			t.Errorf("GET %s = %d %q, want %q", path, code, body, want)
+		}
+	}
+	if code, body := get("/modules/mod/metadata.json"); code != http.StatusOK || !strings.Contains(body, `"1.0.0"`) {
+		t.Errorf("GET metadata.json = %d %q", code, body)
+	}
+	if code, _ := get("/modules/other/metadata.json"); code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing module, got %d", code)
+	}
+
+	code, body := get("/")
+	if code != http.StatusOK || !strings.Contains(body, `id="card-mod"`) || !strings.Contains(body, "/_serve/stamp") {
+		t.Errorf("unexpected index page: %d", code)
+	}
+
+	_, before := get("/_serve/stamp")
+	writeTestFile(t, filepath.Join(modulesDir, "mod", "1.0.0", "presubmit.yml"), "")
+	if _, after := get("/_serve/stamp"); after == before {
+		t.Errorf("expected the stamp to change after a file was added")
+	}
+}
+
+func TestServeURL(t *testing.T) {
+	for addr, want := range map[string]string{
+		":8080":          "http://localhost:8080",
+		"0.0.0.0:8080":   "http://localhost:8080",
+		"[::]:8080":      "http://localhost:8080",
+		"127.0.0.1:9000": "http://127.0.0.1:9000",
+		"example.com:80": "http://example.com:80",
+		"[::1]:8080":     "http://[::1]:8080",
+	} {
+		got, err := serveURL(addr)
+		if err != nil {
+			t.Errorf("serveURL(%q): %v", addr, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("serveURL(%q) = %q, want %q", addr, got, want)
+		}
+	}
+	if _, err := serveURL("8080"); err == nil {
+		t.Error("serveURL(\"8080\") succeeded, want an error")
+	}
+}
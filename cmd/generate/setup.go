@@ -0,0 +1,313 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// bcrURL is the Bazel Central Registry, which users of this registry must
+// list as well.
+const bcrURL = "https://bcr.bazel.build"
+
+var (
+	repoNameRe = regexp.MustCompile(`repo_name\s*=\s*"([^"]+)"`)
+	kwargRe    = regexp.MustCompile(`^(\w+)\s*=([^=].*)$`)
+	literalRe  = regexp.MustCompile(`^(?:"([^"\\]*)"|'([^'\\]*)')$`)
+)
+
+// moduleRepoName returns the repo name that a module gives itself in its
+// MODULE.bazel, if any. Its own labels refer to it by that name.
+func moduleRepoName(moduleFile string) string {
+	if m := moduleCallRe.FindStringSubmatch(moduleFile); m != nil {
+		if r := repoNameRe.FindStringSubmatch(m[1]); r != nil {
+			return r[1]
+		}
+	}
+	return ""
+}
+
+// moduleSetup is what the users of a module need in their MODULE.bazel
+// besides the bazel_dep, as far as it can be told from the MODULE.bazel of
+// the module.
+type moduleSetup struct {
+	// RepoName is the repo name that the module gives itself, if any. Its
+	// own labels refer to it by that name.
+	RepoName string
+	// Extensions are the module extensions that the module defines and
+	// uses itself, with the repos that it imports from them.
+	Extensions []extensionUse
+	// Toolchains are the toolchains of the module that it only registers
+	// as a dev dependency, so that they are not registered for its users.
+	// The toolchains that it registers otherwise are registered for its
+	// users too.
+	Toolchains []string
+}
+
+// extensionUse is a use_extension call, with the repos that are imported
+// from the extension.
+type extensionUse struct {
+	Var   string
+	File  string
+	Name  string
+	Repos []importedRepo
+}
+
+// importedRepo is a repo imported by use_repo: Repo is its name in the
+// extension, and Name the name that it is imported as.
+type importedRepo struct {
+	Name string
+	Repo string
+}
+
+// parseModuleSetup derives the setup of a module named name from its
+// MODULE.bazel. Calls that are computed, for example in a comprehension
+// over labels built with format(), are left out.
+func parseModuleSetup(name, moduleFile string) moduleSetup {
+	moduleFile = stripComments(moduleFile)
+	setup := moduleSetup{RepoName: moduleRepoName(moduleFile)}
+	repo := name
+	if setup.RepoName != "" {
+		repo = setup.RepoName
+	}
+	useRepos := starlarkCalls(moduleFile, "use_repo")
+	ownRepos := make(map[string]bool)
+	for _, call := range starlarkCalls(moduleFile, "use_extension") {
+		if call.Var == "" || len(call.Args) < 2 || call.Kwargs["dev_dependency"] == "True" {
+			continue
+		}
+		label, ok := stringLiteral(call.Args[0])
+		if !ok {
+			continue
+		}
+		file, ok := ownLabel(label, name, repo)
+		if !ok {
+			continue
+		}
+		extName, ok := stringLiteral(call.Args[1])
+		if !ok {
+			continue
+		}
+		ext := extensionUse{Var: call.Var, File: file, Name: extName}
+		for _, u := range useRepos {
+			if len(u.Args) == 0 || u.Args[0] != ext.Var {
+				continue
+			}
+			for _, arg := range u.Args[1:] {
+				if r, ok := stringLiteral(arg); ok {
+					ext.Repos = append(ext.Repos, importedRepo{Name: r, Repo: r})
+				}
+			}
+			for _, key := range u.KwargNames {
+				if r, ok := stringLiteral(u.Kwargs[key]); ok {
+					ext.Repos = append(ext.Repos, importedRepo{Name: key, Repo: r})
+				}
+			}
+		}
+		// Without repos to import, the use_extension is of no use.
+		if len(ext.Repos) == 0 {
+			continue
+		}
+		for _, r := range ext.Repos {
+			ownRepos[r.Name] = true
+		}
+		setup.Extensions = append(setup.Extensions, ext)
+	}
+	for _, call := range starlarkCalls(moduleFile, "register_toolchains") {
+		// Toolchains registered by a module are registered for its users
+		// too, unless they are a dev dependency.
+		if call.Kwargs["dev_dependency"] != "True" {
+			continue
+		}
+		for _, arg := range call.Args {
+			s, ok := stringLiteral(arg)
+			if !ok {
+				continue
+			}
+			if label, ok := ownLabel(s, name, repo); ok {
+				setup.Toolchains = append(setup.Toolchains, label)
+			} else if r, _, ok := strings.Cut(strings.TrimPrefix(s, "@"), "//"); ok && strings.HasPrefix(s, "@") && ownRepos[r] {
+				setup.Toolchains = append(setup.Toolchains, s)
+			}
+		}
+	}
+	return setup
+}
+
+// ownLabel returns label, which is in the MODULE.bazel of the module named
+// name, as seen by the users of the module, who call it repo. It reports
+// false if the label is not in the module.
+func ownLabel(label, name, repo string) (string, bool) {
+	switch {
+	case strings.HasPrefix(label, "//"):
+		return "@" + repo + label, true
+	case strings.HasPrefix(label, ":"):
+		return "@" + repo + "//" + label, true
+	case strings.HasPrefix(label, "@"+name+"//"), strings.HasPrefix(label, "@"+repo+"//"):
+		return "@" + repo + label[strings.Index(label, "//"):], true
+	}
+	return "", false
+}
+
+// starlarkCall is a call of a function in a MODULE.bazel.
+type starlarkCall struct {
+	// Var is the variable that the result is assigned to, if any.
+	Var string
+	// Args are the positional arguments, as source text.
+	Args []string
+	// Kwargs are the keyword arguments, as source text, and KwargNames
+	// their names in the order of the call.
+	Kwargs     map[string]string
+	KwargNames []string
+}
+
+// starlarkCalls returns the calls of the function fn in content, which has
+// no comments.
+func starlarkCalls(content, fn string) []starlarkCall {
+	re := regexp.MustCompile(`(?:\b(\w+)\s*=\s*)?\b` + regexp.QuoteMeta(fn) + `\s*\(`)
+	var calls []starlarkCall
+	for _, m := range re.FindAllStringSubmatchIndex(content, -1) {
+		// Methods, such as ext.use_repo, are other functions.
+		if start := m[0] + strings.LastIndex(content[m[0]:m[1]], fn); start > 0 && content[start-1] == '.' {
+			continue
+		}
+		args, ok := splitArgs(content[m[1]:])
+		if !ok {
+			continue
+		}
+		call := starlarkCall{Kwargs: make(map[string]string)}
+		if m[2] >= 0 {
+			call.Var = content[m[2]:m[3]]
+		}
+		for _, arg := range args {
+			if k := kwargRe.FindStringSubmatch(arg); k != nil {
+				call.Kwargs[k[1]] = strings.TrimSpace(k[2])
+				call.KwargNames = append(call.KwargNames, k[1])
+			} else {
+				call.Args = append(call.Args, arg)
+			}
+		}
+		calls = append(calls, call)
+	}
+	return calls
+}
+
+// splitArgs splits the arguments of a call, which start after its opening
+// parenthesis, at the commas that are not nested in brackets or strings.
+// It reports false if the call is not closed.
+func splitArgs(s string) ([]string, bool) {
+	var args []string
+	depth, start := 0, 0
+	var quote byte
+	add := func(end int) {
+		if arg := strings.TrimSpace(s[start:end]); arg != "" {
+			args = append(args, arg)
+		}
+		start = end + 1
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			if depth == 0 {
+				add(i)
+				return args, true
+			}
+			depth--
+		case c == ',' && depth == 0:
+			add(i)
+		}
+	}
+	return nil, false
+}
+
+// stripComments removes the comments from the Starlark content.
+func stripComments(content string) string {
+	var sb strings.Builder
+	var quote byte
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && i+1 < len(content) {
+				sb.WriteByte(c)
+				i++
+				c = content[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			if i == len(content) {
+				return sb.String()
+			}
+			c = '\n'
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// stringLiteral returns the value of arg, if it is a plain string literal.
+func stringLiteral(arg string) (string, bool) {
+	m := literalRe.FindStringSubmatch(arg)
+	if m == nil {
+		return "", false
+	}
+	return m[1] + m[2], true
+}
+
+// Setup returns what sets up a project to use version v of module m: the
+// registries for its .bazelrc, and the lines for its MODULE.bazel.
+func (b Branding) Setup(m Module, v Version) string {
+	setup := parseModuleSetup(m.Name, v.ModuleFile)
+	var sb strings.Builder
+	// Bazel takes each module version from the first registry that has it,
+	// so the BCR keeps precedence for the versions that it has.
+	sb.WriteString("# .bazelrc\n")
+	fmt.Fprintf(&sb, "common --registry=%s\n", bcrURL)
+	fmt.Fprintf(&sb, "common --registry=%s\n", strings.TrimSuffix(b.RegistryURL, "/"))
+	sb.WriteString("\n# MODULE.bazel\n")
+	if setup.RepoName != "" {
+		fmt.Fprintf(&sb, "bazel_dep(name = %q, version = %q, repo_name = %q)\n", m.Name, v.Name, setup.RepoName)
+	} else {
+		fmt.Fprintf(&sb, "bazel_dep(name = %q, version = %q)\n", m.Name, v.Name)
+	}
+	for _, ext := range setup.Extensions {
+		fmt.Fprintf(&sb, "%s = use_extension(%q, %q)\n", ext.Var, ext.File, ext.Name)
+		fmt.Fprintf(&sb, "use_repo(%s", ext.Var)
+		for _, r := range ext.Repos {
+			if r.Name == r.Repo {
+				fmt.Fprintf(&sb, ", %q", r.Repo)
+			} else {
+				fmt.Fprintf(&sb, ", %s = %q", r.Name, r.Repo)
+			}
+		}
+		sb.WriteString(")\n")
+	}
+	if len(setup.Toolchains) > 0 {
+		sb.WriteString("register_toolchains(")
+		for i, label := range setup.Toolchains {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			fmt.Fprintf(&sb, "%q", label)
+		}
+		sb.WriteString(")\n")
+	}
+	return sb.String()
+}
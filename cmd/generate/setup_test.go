@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSetupModuleFile = `module(
+    name = "rules_x",
+    version = "1.0.0",
+    repo_name = "io_rules_x",
+)
+
+bazel_dep(name = "rules_go", version = "0.50.0", repo_name = "io_bazel_rules_go")
+
+x = use_extension("//x:extensions.bzl", "x")
+x.toolchain(version = "2")
+use_repo(x, "x_toolchains", x_tools = "x_tools_v2")
+
+go_sdk = use_extension("@rules_go//go:extensions.bzl", "go_sdk")
+use_repo(go_sdk, "go_toolchains")
+
+dev = use_extension(":dev.bzl", "dev", dev_dependency = True)
+use_repo(dev, "dev_repo")
+
+# register_toolchains("//x:commented_out", dev_dependency = True)
+register_toolchains("@go_toolchains//:all")
+register_toolchains(
+    "@x_toolchains//:all",
+    "//x:default_toolchain",
+    "@go_toolchains//:all",
+    "@dev_repo//:all",
+    dev_dependency = True,
+)
+[
+    register_toolchains("@x_{}//:all".format(arch), dev_dependency = True)
+    for arch in ["x86_64"]
+]
+`
+
+func TestModuleRepoName(t *testing.T) {
+	if got := moduleRepoName(testSetupModuleFile); got != "io_rules_x" {
+		t.Errorf("moduleRepoName() = %q, want io_rules_x", got)
+	}
+	if got := moduleRepoName(`module(name = "plain")`); got != "" {
+		t.Errorf("moduleRepoName() = %q, want none", got)
+	}
+}
+
+func TestParseModuleSetup(t *testing.T) {
+	want := moduleSetup{
+		RepoName: "io_rules_x",
+		Extensions: []extensionUse{{
+			Var:   "x",
+			File:  "@io_rules_x//x:extensions.bzl",
+			Name:  "x",
+			Repos: []importedRepo{{Name: "x_toolchains", Repo: "x_toolchains"}, {Name: "x_tools", Repo: "x_tools_v2"}},
+		}},
+		Toolchains: []string{"@x_toolchains//:all", "@io_rules_x//x:default_toolchain"},
+	}
+	if got := parseModuleSetup("rules_x", testSetupModuleFile); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseModuleSetup() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	m := Module{Name: "rules_x"}
+	v := Version{Name: "1.0.0", ModuleFile: testSetupModuleFile}
+	got := Branding{RegistryURL: "https://example.com/registry/"}.Setup(m, v)
+	want := `# .bazelrc
+common --registry=https://bcr.bazel.build
+common --registry=https://example.com/registry
+
+# MODULE.bazel
+bazel_dep(name = "rules_x", version = "1.0.0", repo_name = "io_rules_x")
+x = use_extension("@io_rules_x//x:extensions.bzl", "x")
+use_repo(x, "x_toolchains", x_tools = "x_tools_v2")
+register_toolchains("@x_toolchains//:all", "@io_rules_x//x:default_toolchain")
+`
+	if got != want {
+		t.Errorf("Setup() = %s, want %s", got, want)
+	}
+
+	v.ModuleFile = `module(name = "plain")`
+	if got := (Branding{}).Setup(Module{Name: "plain"}, v); !strings.HasSuffix(got, "\n# MODULE.bazel\n"+`bazel_dep(name = "plain", version = "1.0.0")`+"\n") {
+		t.Errorf("expected only the bazel_dep for a module without setup, got %s", got)
+	}
+}
+
+// TestSetupRegistryModules checks the setup of modules in this registry.
+func TestSetupRegistryModules(t *testing.T) {
+	for _, test := range []struct {
+		module, version string
+		want            string
+	}{
+		// An extension of the module itself, with the repos it imports.
+		{"bazel_bats", "0.38.23", `bazel_dep(name = "bazel_bats", version = "0.38.23")
+bazel_bats = use_extension("@bazel_bats//:extensions.bzl", "bazel_bats_deps")
+use_repo(bazel_bats, "bats_core", "bats_assert", "bats_support")
+`},
+		// The toolchains from another module's extension are left out.
+		{"rules_raylib", "0.0.22", `bazel_dep(name = "rules_raylib", version = "0.0.22")
+local = use_extension("@rules_raylib//:extensions.bzl", "raylib")
+use_repo(local, "raylib_src")
+`},
+		// A toolchain that is not a dev dependency is registered for users.
+		{"rules_ghdl", "1.2.4", `bazel_dep(name = "rules_ghdl", version = "1.2.4")
+`},
+		// Dev dependency extensions and toolchains of other modules.
+		{"rules_nvc", "4.5.1", `bazel_dep(name = "rules_nvc", version = "4.5.1")
+`},
+		{"nvc", "1.22.0.bcr.2", `bazel_dep(name = "nvc", version = "1.22.0.bcr.2")
+`},
+		// Commented out calls.
+		{"elfutils", "0.1.0", `bazel_dep(name = "elfutils", version = "0.1.0")
+`},
+		// Calls in a comprehension, with computed labels.
+		{"elfutils", "0.1.4", `bazel_dep(name = "elfutils", version = "0.1.4")
+`},
+	} {
+		content, err := os.ReadFile(filepath.Join("..", "..", "modules", test.module, test.version, "MODULE.bazel"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := (Branding{}).Setup(Module{Name: test.module}, Version{Name: test.version, ModuleFile: string(content)})
+		if _, got, _ = strings.Cut(got, "# MODULE.bazel\n"); got != test.want {
+			t.Errorf("Setup() of %s@%s = %s, want %s", test.module, test.version, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+)
+
+func runSyncMetadata(args []string) error {
+	var (
+		modulesDir    string
+		module        string
+		removeMissing bool
+	)
+	fs := flag.NewFlagSet("sync-metadata", flag.ExitOnError)
+	fs.StringVar(&modulesDir, "modules_dir", "modules", "The path to the modules directory.")
+	fs.StringVar(&module, "module", "", "The module to sync. All modules are synced if empty.")
+	fs.BoolVar(&removeMissing, "remove_missing", false, "Remove listed versions that have no version directory.")
+	fs.Parse(args)
+
+	var modules []Module
+	if module != "" {
+		m, err := loadModule(modulesDir, module)
+		if err != nil {
+			return fmt.Errorf("module %s: %w", module, err)
+		}
+		modules = append(modules, m)
+	} else {
+		var err error
+		modules, err = findModules(modulesDir)
+		if err != nil {
+			return err
+		}
+	}
+	for _, m := range modules {
+		added, missing, err := syncMetadata(modulesDir, m, removeMissing)
+		if err != nil {
+			return fmt.Errorf("module %s: %w", m.Name, err)
+		}
+		for _, v := range added {
+			log.Printf("%s: added version %s", m.Name, v)
+		}
+		for _, v := range missing {
+			if removeMissing {
+				log.Printf("%s: removed version %s", m.Name, v)
+			} else {
+				log.Printf("%s: version %s has no directory, use --remove_missing to remove it", m.Name, v)
+			}
+		}
+	}
+	return nil
+}
+
+// syncMetadata makes the versions listed in metadata.json of a module match
+// its version directories. Returns the versions that were added, and the
+// listed versions that have no directory. The latter are only removed if
+// removeMissing is set.
+func syncMetadata(modulesDir string, m Module, removeMissing bool) (added, missing []string, err error) {
+	found := make(map[string]bool)
+	for _, v := range m.Versions {
+		found[v.Name] = true
+	}
+	listed := make(map[string]bool)
+	versions := []string{}
+	for _, v := range m.Metadata.Versions {
+		listed[v] = true
+		if !found[v] {
+			missing = append(missing, v)
+			if removeMissing {
+				continue
+			}
+		}
+		versions = append(versions, v)
+	}
+	for _, v := range m.Versions {
+		if !listed[v.Name] {
+			added = append(added, v.Name)
+			versions = append(versions, v.Name)
+		}
+	}
+	sortVersions(added)
+	if len(added) == 0 && (len(missing) == 0 || !removeMissing) {
+		return added, missing, nil
+	}
+
+	metadataPath := filepath.Join(modulesDir, m.Name, "metadata.json")
+	metadata, err := readJSONObject(metadataPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	sortVersions(versions)
+	if err := metadata.Set("versions", versions); err != nil {
+		return nil, nil, err
+	}
+	if removeMissing {
+		yanked := &jsonObject{}
+		if err := metadata.Get("yanked_versions", yanked); err != nil {
+			return nil, nil, err
+		}
+		for _, v := range missing {
+			yanked.Delete(v)
+		}
+		if metadata.Has("yanked_versions") {
+			if err := metadata.Set("yanked_versions", yanked); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return added, missing, writeJSONObject(metadataPath, metadata)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSyncMetadata(t *testing.T) {
+	modulesDir := t.TempDir()
+	writeTestModule(t, modulesDir, "mod", map[string]string{
+		"1.0.0":  `module(name = "mod", version = "1.0.0")`,
+		"1.10.0": `module(name = "mod", version = "1.10.0")`,
+	})
+	// 1.2.0 is on disk but not listed, 0.9.0 is listed but not on disk.
+	writeTestFile(t, filepath.Join(modulesDir, "mod", "1.2.0", "MODULE.bazel"), `module(name = "mod", version = "1.2.0")`)
+	writeTestFile(t, filepath.Join(modulesDir, "mod", "1.2.0", "source.json"), testSource("mod", "1.2.0"))
+	if err := os.RemoveAll(filepath.Join(modulesDir, "mod", "1.0.0")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(modulesDir, "mod", "0.9.0", "README"), "")
+
+	metadataPath := filepath.Join(modulesDir, "mod", "metadata.json")
+	metadata, _ := readJSONObject(metadataPath)
+	metadata.Set("versions", []string{"0.9.0", "1.0.0", "1.10.0"})
+	metadata.Set("yanked_versions", map[string]string{"0.9.0": "old"})
+	writeJSONObject(metadataPath, metadata)
+
+	sync := func(removeMissing bool) (added, missing []string, m Module) {
+		t.Helper()
+		m, err := loadModule(modulesDir, "mod")
+		if err != nil {
+			t.Fatal(err)
+		}
+		added, missing, err = syncMetadata(modulesDir, m, removeMissing)
+		if err != nil {
+			t.Fatalf("syncMetadata failed: %v", err)
+		}
+		m, _ = loadModule(modulesDir, "mod")
+		return added, missing, m
+	}
+
+	added, missing, m := sync(false)
+	if !reflect.DeepEqual(added, []string{"1.2.0"}) || !reflect.DeepEqual(missing, []string{"0.9.0", "1.0.0"}) {
+		t.Errorf("added = %v, missing = %v", added, missing)
+	}
+	if want := []string{"0.9.0", "1.0.0", "1.2.0", "1.10.0"}; !reflect.DeepEqual(m.Metadata.Versions, want) {
+		t.Errorf("versions = %v, want %v", m.Metadata.Versions, want)
+	}
+
+	_, _, m = sync(true)
+	if want := []string{"1.2.0", "1.10.0"}; !reflect.DeepEqual(m.Metadata.Versions, want) {
+		t.Errorf("versions = %v, want %v", m.Metadata.Versions, want)
+	}
+	if len(m.Metadata.YankedVersions) != 0 {
+		t.Errorf("expected yanked entries of removed versions to be gone, got: %v", m.Metadata.YankedVersions)
+	}
+	if err := validateModule(m); err != nil {
+		t.Errorf("expected a valid module after sync, got: %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// parseTemplates parses all the page templates, which share the layout
+// templates. If templateDir is set, each *.html file in it is then parsed
+// as the template named after the file, and the templates it defines
+// replace the built-in templates of the same name.
+func parseTemplates(templateDir string) (*template.Template, error) {
+	tmpl := template.New("").Funcs(template.FuncMap{
+		"isURL": func(s string) bool {
+			return strings.HasPrefix(s, "http")
+		},
+		"sub": func(a, b int) int {
+			return a - b
+		},
+		"repoURL": repoURL,
+		"bazelDep": func(name, version string) string {
+			return fmt.Sprintf(`bazel_dep(name = "%s", version = "%s")`, name, version)
+		},
+		"isYanked": func(version string, metadata Metadata) bool {
+			_, ok := metadata.YankedVersions[version]
+			return ok
+		},
+		"sanitizeID":        sanitizeID,
+		"presubmitMatrix":   presubmitMatrix,
+		"highlightStarlark": highlightStarlark,
+		"highlightDiff":     highlightDiff,
+	})
+	for _, t := range []struct{ name, text string }{
+		{"index", htmlTemplate},
+		{"layout", layoutTemplate},
+		{"module", moduleTemplate},
+		{"version", versionTemplate},
+		{"changelog", changelogTemplate},
+	} {
+		if _, err := tmpl.New(t.name).Parse(t.text); err != nil {
+			return nil, fmt.Errorf("failed to parse %s template: %w", t.name, err)
+		}
+	}
+	if templateDir == "" {
+		return tmpl, nil
+	}
+	files, err := filepath.Glob(filepath.Join(templateDir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(file), ".html")
+		if _, err := tmpl.New(name).Parse(string(content)); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+	}
+	return tmpl, nil
+}
+
+// executeTemplate renders the named page template of tmpl, see
+// parseTemplates, into w.
+func executeTemplate(tmpl *template.Template, name string, data interface{}, w io.Writer) error {
+	var buf strings.Builder
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return fmt.Errorf("failed to execute HTML template: %w", err)
+	}
+
+	if _, err := io.WriteString(w, buf.String()); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
+
+// layoutTemplate holds the parts shared by all pages. The "head" and
+// "footer" templates expect the page data to have the Title, Root and Site
+// fields, where Root is the relative path from the page to the index page's
+// directory.
+const layoutTemplate = `
+{{define "head"}}
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{if .Title}}{{.Title}} - {{end}}{{.Site.Title}}</title>
+	{{if .Site.SelfContained}}
+	{{.Site.Stylesheet .Root "bootstrap.min.css"}}
+	{{.Site.Stylesheet .Root "bootstrap-icons.min.css"}}
+	{{else}}
+    <link
+		href="https://cdn.jsdelivr.net/npm/bootstrap@5.3.3/dist/css/bootstrap.min.css"
+		rel="stylesheet"
+	>
+    <link
+		href="https://cdn.jsdelivr.net/npm/bootstrap-icons@1.11.3/font/bootstrap-icons.min.css"
+		rel="stylesheet"
+	>
+	{{end}}
+	<link rel="icon" href="{{.Root}}{{.Site.Favicon}}" type="image/png">
+	{{with .Site.AnalyticsID}}
+	<!-- Google tag (gtag.js) -->
+	<script async src="https://www.googletagmanager.com/gtag/js?id={{.}}"></script>
+	<script>
+	  window.dataLayer = window.dataLayer || [];
+	  function gtag(){dataLayer.push(arguments);}
+	  gtag('js', new Date());
+
+	  gtag('config', '{{.}}');
+	</script>
+	{{end}}
+	{{.Site.Script .Root "registry.js"}}
+	{{.Site.Stylesheet .Root "registry.css"}}
+{{end}}
+
+{{define "bootstrapScript"}}
+    {{if .Site.SelfContained}}
+    {{.Site.Script .Root "bootstrap.bundle.min.js"}}
+    {{else}}
+    <script src="https://cdn.jsdelivr.net/npm/bootstrap@5.3.3/dist/js/bootstrap.bundle.min.js"></script>
+    {{end}}
+{{end}}
+
+{{define "themeToggle"}}
+			<button class="btn btn-outline-secondary" onclick="toggleTheme()" id="themeToggle" title="Toggle theme">
+				<i class="bi bi-circle-half"></i>
+			</button>
+{{end}}
+
+{{/* timeAgo shows a time as its date, which registry.js replaces with how
+long ago it was, so that the pages do not depend on when they were built. */}}
+{{define "timeAgo"}}<time class="time-ago" datetime="{{.Format "2006-01-02T15:04:05Z07:00"}}" title="{{.Format "2006-01-02"}}">{{.Format "2006-01-02"}}</time>{{end}}
+
+{{define "footer"}}
+    <footer class="text-center mt-4 py-3">
+        {{.Site.Footer}}
+    </footer>
+{{end}}
+`
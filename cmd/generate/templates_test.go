@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBranding(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	writeTestFile(t, configPath, `{"site": {
+		"title": "Team Registry",
+		"heading": "<b>Team</b> Registry",
+		"browse_url": "https://git.example.com/registry/-/tree/main/modules",
+		"footer": "<p>Internal use only.</p>",
+		"analytics_id": "G-TEAM"
+	}}`)
+	config, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modules := []Module{testGraphModule("lib", "1.0.0")}
+	modules[0].Metadata.Repo = []string{"github:o/lib"}
+
+	var buf bytes.Buffer
+	if err := writeHTML(testTemplates(t, ""), TemplateData{Site: SiteOptions{Branding: config.Site}, Modules: modules}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+	for _, want := range []string{
+		`<title>Team Registry</title>`,
+		`<h1 class="mb-0"><b>Team</b> Registry</h1>`,
+		`href="https://git.example.com/registry/-/tree/main/modules/lib/1.0.0"`,
+		`<p>Internal use only.</p>`,
+		`gtag/js?id=G-TEAM`,
+		// Not set in the config.
+		`href="hdlfactory.png"`,
+		`href="https://github.com/filmil/bazel-registry#usage"`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("expected the index page to contain %q", want)
+		}
+	}
+	if strings.Contains(page, "Filip Filmar") {
+		t.Errorf("expected the default footer to be replaced")
+	}
+
+	feed := buildFeed(modules, config.Site)
+	if feed.Title != "Team Registry releases" || feed.ID != defaultBranding.SiteURL+"feed.xml" {
+		t.Errorf("unexpected feed title %q or ID %q", feed.Title, feed.ID)
+	}
+}
+
+func TestTemplateDir(t *testing.T) {
+	templateDir := t.TempDir()
+	writeTestFile(t, filepath.Join(templateDir, "footer.html"), `<footer>Custom footer for {{.Site.Title}}</footer>`)
+	writeTestFile(t, filepath.Join(templateDir, "changelog.html"), `{{define "changelogEvents"}}<ol>{{range .Events}}<li>{{.Module}}</li>{{end}}</ol>{{end}}`)
+	writeTestFile(t, filepath.Join(templateDir, "notes.txt"), `{{template "missing"}}`)
+	site := SiteOptions{TemplateDir: templateDir}
+	tmpl := testTemplates(t, templateDir)
+
+	var buf bytes.Buffer
+	data := TemplateData{Site: site, WhatsNew: &ChangelogPageData{Events: []ChangeEvent{{Kind: eventModule, Module: "lib"}}}}
+	if err := writeHTML(tmpl, data, &buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<footer>Custom footer for Bazel Registry</footer>`,
+		`<ol><li>lib</li></ol>`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected the index page to contain %q", want)
+		}
+	}
+
+	outputDir := t.TempDir()
+	if err := writeModulePages(tmpl, []Module{testGraphModule("lib", "1.0.0")}, nil, outputDir, site); err != nil {
+		t.Fatal(err)
+	}
+	page, err := os.ReadFile(filepath.Join(outputDir, "lib", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), `<footer>Custom footer for Bazel Registry</footer>`) {
+		t.Errorf("expected the module page to use the custom footer")
+	}
+
+	writeTestFile(t, filepath.Join(templateDir, "layout.html"), `{{define "head"}}`)
+	if _, err := parseTemplates(templateDir); err == nil {
+		t.Errorf("expected an error for a malformed template")
+	}
+}
+
+// testTemplates parses the templates, see parseTemplates.
+func testTemplates(t *testing.T, templateDir string) *template.Template {
+	t.Helper()
+	tmpl, err := parseTemplates(templateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tmpl
+}
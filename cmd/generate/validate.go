@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// validateModule checks a module for problems that make the registry
+// inconsistent. All problems found are reported in the returned error.
+func validateModule(m Module) error {
+	var errs []error
+	md := m.Metadata
+	if !isHTTPURL(md.Homepage) {
+		errs = append(errs, fmt.Errorf("invalid homepage: %q", md.Homepage))
+	}
+	if len(md.Repo) == 0 {
+		errs = append(errs, fmt.Errorf("no repository listed"))
+	}
+	for _, repo := range md.Repo {
+		if !validRepository(repo) {
+			errs = append(errs, fmt.Errorf("invalid repository: %q", repo))
+		}
+	}
+	if len(md.Maintainers) == 0 {
+		errs = append(errs, fmt.Errorf("no maintainers listed"))
+	}
+	for i, maintainer := range md.Maintainers {
+		if maintainer.GitHub == "" && maintainer.Email == "" {
+			errs = append(errs, fmt.Errorf("maintainer %d has neither github nor email", i))
+		}
+	}
+
+	for _, tag := range md.Tags {
+		if !validTag.MatchString(tag) {
+			errs = append(errs, fmt.Errorf("invalid tag %q, want lowercase letters, digits and dashes", tag))
+		}
+	}
+
+	if md.ReplacedBy != "" && md.Deprecated == "" {
+		errs = append(errs, fmt.Errorf("replaced_by is set, but the module is not deprecated"))
+	}
+
+	listed := make(map[string]bool)
+	for _, v := range md.Versions {
+		listed[v] = true
+	}
+	found := make(map[string]bool)
+	for _, v := range m.Versions {
+		found[v.Name] = true
+		if !listed[v.Name] {
+			errs = append(errs, fmt.Errorf("version %s is not listed in metadata.json", v.Name))
+		}
+		if name := moduleName(v.ModuleFile); name != m.Name {
+			errs = append(errs, fmt.Errorf("version %s: MODULE.bazel declares module %q", v.Name, name))
+		}
+		if version := moduleVersion(v.ModuleFile); version != v.Name {
+			errs = append(errs, fmt.Errorf("version %s: MODULE.bazel declares version %q", v.Name, version))
+		}
+		if v.Source.URL == "" {
+			errs = append(errs, fmt.Errorf("version %s: source.json has no url", v.Name))
+		}
+		if err := checkIntegrity(v.Source.Integrity); err != nil {
+			errs = append(errs, fmt.Errorf("version %s: source.json: %w", v.Name, err))
+		}
+		if v.AttestationsFile != "" {
+			if _, err := parseAttestations([]byte(v.AttestationsFile), v.Source); err != nil {
+				errs = append(errs, fmt.Errorf("version %s: %s: %w", v.Name, attestationsFile, err))
+			}
+		}
+	}
+	for _, v := range md.Versions {
+		if !found[v] {
+			errs = append(errs, fmt.Errorf("version %s is listed in metadata.json but has no directory", v))
+		}
+	}
+	for v := range md.YankedVersions {
+		if !listed[v] {
+			errs = append(errs, fmt.Errorf("yanked version %s is not listed in metadata.json", v))
+		}
+	}
+	for v, date := range md.PublishedAt {
+		if !listed[v] {
+			errs = append(errs, fmt.Errorf("published_at: version %s is not listed in metadata.json", v))
+		}
+		if _, err := parsePublishedAt(date); err != nil {
+			errs = append(errs, fmt.Errorf("published_at: version %s: %w", v, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// moduleName returns the name declared in the module() call of a
+// MODULE.bazel file.
+func moduleName(moduleFile string) string {
+	return moduleAttr(moduleFile, moduleNameAttrRe)
+}
+
+// moduleVersion returns the version declared in the module() call of a
+// MODULE.bazel file.
+func moduleVersion(moduleFile string) string {
+	return moduleAttr(moduleFile, moduleVersionAttrRe)
+}
+
+func moduleAttr(moduleFile string, re *regexp.Regexp) string {
+	call := moduleCallRe.FindStringSubmatch(moduleFile)
+	if call == nil {
+		return ""
+	}
+	if match := re.FindStringSubmatch(call[1]); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validRepository accepts the repository forms allowed by metadata.json:
+// "github:owner/repo" or a URL.
+func validRepository(repo string) bool {
+	if rest, ok := strings.CutPrefix(repo, "github:"); ok {
+		owner, name, ok := strings.Cut(rest, "/")
+		return ok && owner != "" && name != "" && !strings.Contains(name, "/")
+	}
+	return isHTTPURL(repo)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestModuleAttributes(t *testing.T) {
+	moduleFile := `module(
+    repo_name = "io_mod",
+    name = "mod",
+    version = "",
+)`
+	if got := moduleName(moduleFile); got != "mod" {
+		t.Errorf("moduleName() = %q, want mod", got)
+	}
+	if got := moduleVersion(moduleFile); got != "" {
+		t.Errorf("moduleVersion() = %q, want an empty version", got)
+	}
+	if got := moduleName(`bazel_dep(name = "dep")`); got != "" {
+		t.Errorf("moduleName() = %q without a module() call", got)
+	}
+}
+
+func TestValidateModule(t *testing.T) {
+	m := Module{
+		Name: "mod",
+		Metadata: Metadata{
+			Homepage:    "https://example.com",
+			Maintainers: []Maintainer{{GitHub: "jdoe"}},
+			Repo:        []string{"github:jdoe/mod"},
+			Versions:    []string{"1.0.0"},
+		},
+		Versions: []Version{{
+			Name:       "1.0.0",
+			ModuleFile: `module(name = "mod", version = "1.0.0")`,
+			Source: Source{
+				URL:       "https://example.com/mod.tar.gz",
+				Integrity: "sha256-r8OAAxBZdAzdRnKhwxb7gIHvlrERw7yEyMf3My1SJbo=",
+			},
+		}},
+	}
+	if err := validateModule(m); err != nil {
+		t.Errorf("expected a valid module, got: %v", err)
+	}
+
+	m.Metadata.Homepage = "https.://example.com"
+	m.Metadata.Repo = []string{"github:jdoe"}
+	m.Metadata.Versions = []string{"0.9.0"}
+	m.Metadata.YankedVersions = map[string]string{"0.8.0": "broken"}
+	m.Metadata.PublishedAt = map[string]string{"0.9.0": "last tuesday", "0.7.0": "2025-01-01"}
+	m.Versions[0].ModuleFile = `module(name = "other", version = "0.1")`
+	m.Versions[0].AttestationsFile = `{"mediaType": "text/plain"}`
+	err := validateModule(m)
+	if err == nil {
+		t.Fatalf("expected an invalid module")
+	}
+	for _, want := range []string{
+		"invalid homepage",
+		"invalid repository",
+		"version 1.0.0 is not listed",
+		`declares module "other"`,
+		`declares version "0.1"`,
+		"version 0.9.0 is listed in metadata.json but has no directory",
+		"yanked version 0.8.0",
+		`published_at: version 0.9.0: malformed publication date "last tuesday"`,
+		"published_at: version 0.7.0 is not listed",
+		"version 1.0.0: attestations.json: unsupported mediaType",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %q, got:\n%v", want, err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// compareVersions compares two module versions using the Bazel module
+// version ordering: RELEASE[-PRERELEASE][+BUILD], where each part is a
+// dot-separated list of identifiers. Returns -1, 0 or 1.
+func compareVersions(a, b string) int {
+	a, _, _ = strings.Cut(a, "+")
+	b, _, _ = strings.Cut(b, "+")
+	aRelease, aPre, aHasPre := strings.Cut(a, "-")
+	bRelease, bPre, bHasPre := strings.Cut(b, "-")
+
+	if c := compareIdentifiers(aRelease, bRelease); c != 0 {
+		return c
+	}
+	switch {
+	case aHasPre && !bHasPre:
+		return -1
+	case !aHasPre && bHasPre:
+		return 1
+	}
+	return compareIdentifiers(aPre, bPre)
+}
+
+// compareIdentifiers compares dot-separated identifier lists. Numeric
+// identifiers compare numerically and sort before alphanumeric ones.
+func compareIdentifiers(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.ParseUint(as[i], 10, 64)
+		bn, bErr := strconv.ParseUint(bs[i], 10, 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				if an < bn {
+					return -1
+				}
+				return 1
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+// sortVersions sorts versions in ascending order, as metadata.json lists them.
+func sortVersions(versions []string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) < 0
+	})
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"1.0.0", "1.0.1", -1},
+		{"43.0.10", "43.0.9", 1},
+		{"1.0", "1.0.0", -1},
+		{"1.0.0-rc1", "1.0.0", -1},
+		{"1.0.0+build", "1.0.0", 0},
+		{"1.22.0.bcr.1", "1.22.0.bcr.2", -1},
+		{"1.22.0.bcr.0", "1.22.0", 1},
+		{"1.0.0", "1.0.a", -1},
+	}
+	for _, test := range tests {
+		if got := compareVersions(test.a, test.b); got != test.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestSortVersions(t *testing.T) {
+	versions := []string{"0.10.0", "0.2.0", "0.9.1", "0.9.1-rc1"}
+	sortVersions(versions)
+	want := []string{"0.2.0", "0.9.1-rc1", "0.9.1", "0.10.0"}
+	if !reflect.DeepEqual(versions, want) {
+		t.Errorf("sortVersions() = %v, want %v", versions, want)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// VersionDiff describes the changes between two versions of a module.
+type VersionDiff struct {
+	Module string
+	From   string
+	To     string
+
+	AddedDeps   []Dependency
+	RemovedDeps []Dependency
+	ChangedDeps []DependencyChange
+
+	// ModuleFileDiff is a unified diff of MODULE.bazel, or empty if the
+	// files are equal.
+	ModuleFileDiff string
+	SourceChanges  []FieldChange
+
+	Patches FileSetDiff
+	Overlay FileSetDiff
+}
+
+// DependencyChange is a dependency whose version or kind changed.
+type DependencyChange struct {
+	Name string
+	From Dependency
+	To   Dependency
+}
+
+// FieldChange is a changed field of source.json.
+type FieldChange struct {
+	Field string
+	From  string
+	To    string
+}
+
+// FileSetDiff describes the changes to a set of files, such as the patches
+// of a version.
+type FileSetDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// Empty reports whether the file sets are equal.
+func (d FileSetDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Empty reports whether the versions have no differences.
+func (d VersionDiff) Empty() bool {
+	return len(d.AddedDeps) == 0 && len(d.RemovedDeps) == 0 && len(d.ChangedDeps) == 0 &&
+		d.ModuleFileDiff == "" && len(d.SourceChanges) == 0 &&
+		d.Patches.Empty() && d.Overlay.Empty()
+}
+
+func runDiff(args []string) error {
+	var (
+		modulesDir string
+		module     string
+		from       string
+		to         string
+	)
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.StringVar(&modulesDir, "modules_dir", "modules", "The path to the modules directory.")
+	fs.StringVar(&module, "module", "", "The name of the module.")
+	fs.StringVar(&from, "from", "", "The version to compare from. Defaults to the version before --to.")
+	fs.StringVar(&to, "to", "", "The version to compare to. Defaults to the newest version.")
+	fs.Parse(args)
+
+	if module == "" {
+		return fmt.Errorf("flag --module=... is required")
+	}
+	m, err := loadModule(modulesDir, module)
+	if err != nil {
+		return fmt.Errorf("module %s: %w", module, err)
+	}
+	oldVersion, newVersion, err := diffVersionPair(m, from, to)
+	if err != nil {
+		return err
+	}
+	return writeVersionDiff(os.Stdout, diffVersions(m.Name, oldVersion, newVersion))
+}
+
+// diffVersionPair finds the versions of m to compare. An empty to is the
+// newest version, and an empty from is the version just before to.
+func diffVersionPair(m Module, from, to string) (Version, Version, error) {
+	if len(m.Versions) == 0 {
+		return Version{}, Version{}, fmt.Errorf("module %s has no versions", m.Name)
+	}
+	// Versions are sorted newest first.
+	toIndex := 0
+	if to != "" {
+		toIndex = findVersionIndex(m.Versions, to)
+		if toIndex < 0 {
+			return Version{}, Version{}, fmt.Errorf("module %s has no version %s", m.Name, to)
+		}
+	}
+	fromIndex := toIndex + 1
+	if from != "" {
+		fromIndex = findVersionIndex(m.Versions, from)
+		if fromIndex < 0 {
+			return Version{}, Version{}, fmt.Errorf("module %s has no version %s", m.Name, from)
+		}
+	} else if fromIndex >= len(m.Versions) {
+		return Version{}, Version{}, fmt.Errorf("module %s has no version before %s", m.Name, m.Versions[toIndex].Name)
+	}
+	return m.Versions[fromIndex], m.Versions[toIndex], nil
+}
+
+func findVersionIndex(versions []Version, name string) int {
+	for i, v := range versions {
+		if v.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// diffVersions compares the versions from and to of a module.
+func diffVersions(module string, from, to Version) VersionDiff {
+	d := VersionDiff{
+		Module: module,
+		From:   from.Name,
+		To:     to.Name,
+		ModuleFileDiff: unifiedDiff(
+			module+"/"+from.Name+"/MODULE.bazel",
+			module+"/"+to.Name+"/MODULE.bazel",
+			from.ModuleFile, to.ModuleFile),
+		Patches: diffFileSets(
+			checkFiles(from.Patches, from.Source.Patches),
+			checkFiles(to.Patches, to.Source.Patches)),
+		Overlay: diffFileSets(
+			checkFiles(from.Overlay, from.Source.Overlay),
+			checkFiles(to.Overlay, to.Source.Overlay)),
+	}
+
+	oldDeps := make(map[string]Dependency)
+	for _, dep := range from.Dependencies {
+		oldDeps[dep.Name] = dep
+	}
+	newDeps := make(map[string]bool)
+	for _, dep := range to.Dependencies {
+		newDeps[dep.Name] = true
+		old, ok := oldDeps[dep.Name]
+		switch {
+		case !ok:
+			d.AddedDeps = append(d.AddedDeps, dep)
+		case old != dep:
+			d.ChangedDeps = append(d.ChangedDeps, DependencyChange{Name: dep.Name, From: old, To: dep})
+		}
+	}
+	for _, dep := range from.Dependencies {
+		if !newDeps[dep.Name] {
+			d.RemovedDeps = append(d.RemovedDeps, dep)
+		}
+	}
+
+	for _, f := range []FieldChange{
+		{"url", from.Source.URL, to.Source.URL},
+		{"integrity", from.Source.Integrity, to.Source.Integrity},
+		{"strip_prefix", from.Source.StripPrefix, to.Source.StripPrefix},
+		{"patch_strip", strconv.Itoa(from.Source.PatchStrip), strconv.Itoa(to.Source.PatchStrip)},
+		{"docs_url", from.Source.DocsURL, to.Source.DocsURL},
+	} {
+		if f.From != f.To {
+			d.SourceChanges = append(d.SourceChanges, f)
+		}
+	}
+	return d
+}
+
+// diffFileSets compares two sets of files by name and hash. Files that only
+// exist in source.json are compared by their declared hash.
+func diffFileSets(from, to []CheckedFile) FileSetDiff {
+	hash := func(f CheckedFile) string {
+		if f.Exists {
+			return f.Integrity
+		}
+		return f.Declared
+	}
+	oldHashes := make(map[string]string)
+	for _, f := range from {
+		oldHashes[f.Name] = hash(f)
+	}
+	var d FileSetDiff
+	for _, f := range to {
+		old, ok := oldHashes[f.Name]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, f.Name)
+		case old != hash(f):
+			d.Changed = append(d.Changed, f.Name)
+		}
+		delete(oldHashes, f.Name)
+	}
+	for name := range oldHashes {
+		d.Removed = append(d.Removed, name)
+	}
+	sort.Strings(d.Removed)
+	return d
+}
+
+// writeVersionDiff writes a human-readable report of d to w.
+func writeVersionDiff(w io.Writer, d VersionDiff) error {
+	var sb strings.Builder
+	p := func(format string, args ...interface{}) {
+		fmt.Fprintf(&sb, format, args...)
+	}
+	p("%s %s -> %s\n", d.Module, d.From, d.To)
+	if d.Empty() {
+		p("\nNo changes.\n")
+	}
+	if len(d.AddedDeps)+len(d.RemovedDeps)+len(d.ChangedDeps) > 0 {
+		p("\nDependencies:\n")
+		for _, dep := range d.AddedDeps {
+			p("  + %s %s%s\n", dep.Name, dep.Version, devSuffix(dep))
+		}
+		for _, dep := range d.RemovedDeps {
+			p("  - %s %s%s\n", dep.Name, dep.Version, devSuffix(dep))
+		}
+		for _, c := range d.ChangedDeps {
+			p("  ~ %s %s%s -> %s%s\n", c.Name, c.From.Version, devSuffix(c.From), c.To.Version, devSuffix(c.To))
+		}
+	}
+	if len(d.SourceChanges) > 0 {
+		p("\nsource.json:\n")
+		for _, c := range d.SourceChanges {
+			p("  %s: %q -> %q\n", c.Field, c.From, c.To)
+		}
+	}
+	for _, set := range []struct {
+		name string
+		diff FileSetDiff
+	}{
+		{"Patches", d.Patches},
+		{"Overlay", d.Overlay},
+	} {
+		if set.diff.Empty() {
+			continue
+		}
+		p("\n%s:\n", set.name)
+		for _, name := range set.diff.Added {
+			p("  + %s\n", name)
+		}
+		for _, name := range set.diff.Removed {
+			p("  - %s\n", name)
+		}
+		for _, name := range set.diff.Changed {
+			p("  ~ %s\n", name)
+		}
+	}
+	if d.ModuleFileDiff != "" {
+		p("\nMODULE.bazel:\n%s", d.ModuleFileDiff)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func devSuffix(dep Dependency) string {
+	if dep.DevDependency {
+		return " (dev)"
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffVersions(t *testing.T) {
+	modulesDir := t.TempDir()
+	writeTestModule(t, modulesDir, "nvc", map[string]string{
+		"1.22.0.bcr.1": `module(name = "nvc", version = "1.22.0.bcr.1")
+bazel_dep(name = "gone", version = "1.0")
+bazel_dep(name = "lib", version = "1.0")
+bazel_dep(name = "rules_cc", version = "0.1")
+`,
+		"1.22.0.bcr.2": `module(name = "nvc", version = "1.22.0.bcr.2")
+bazel_dep(name = "lib", version = "1.1")
+bazel_dep(name = "new", version = "2.0", dev_dependency = True)
+bazel_dep(name = "rules_cc", version = "0.1")
+`,
+	})
+	for _, v := range []string{"1.22.0.bcr.1", "1.22.0.bcr.2"} {
+		writeTestFile(t, filepath.Join(modulesDir, "nvc", v, "patches", "keep.patch"), "keep\n")
+		writeTestFile(t, filepath.Join(modulesDir, "nvc", v, "overlay", "BUILD.bazel"), "# "+v+"\n")
+	}
+	writeTestFile(t, filepath.Join(modulesDir, "nvc", "1.22.0.bcr.1", "patches", "wave_fst_length_guard.patch"), "guard\n")
+	writeTestFile(t, filepath.Join(modulesDir, "nvc", "1.22.0.bcr.2", "patches", "added.patch"), "added\n")
+	m, err := loadModule(modulesDir, "nvc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from, to, err := diffVersionPair(m, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from.Name != "1.22.0.bcr.1" || to.Name != "1.22.0.bcr.2" {
+		t.Fatalf("diffVersionPair() = %s, %s", from.Name, to.Name)
+	}
+	if _, _, err := diffVersionPair(m, "", "1.22.0.bcr.1"); err == nil {
+		t.Errorf("expected an error when there is no version before --to")
+	}
+	if _, _, err := diffVersionPair(m, "9.9", ""); err == nil {
+		t.Errorf("expected an error for an unknown --from version")
+	}
+
+	d := diffVersions(m.Name, from, to)
+	if want := []Dependency{{Name: "new", Version: "2.0", DevDependency: true}}; !reflect.DeepEqual(d.AddedDeps, want) {
+		t.Errorf("AddedDeps = %v, want %v", d.AddedDeps, want)
+	}
+	if want := []Dependency{{Name: "gone", Version: "1.0"}}; !reflect.DeepEqual(d.RemovedDeps, want) {
+		t.Errorf("RemovedDeps = %v, want %v", d.RemovedDeps, want)
+	}
+	if len(d.ChangedDeps) != 1 || d.ChangedDeps[0].Name != "lib" || d.ChangedDeps[0].To.Version != "1.1" {
+		t.Errorf("ChangedDeps = %v", d.ChangedDeps)
+	}
+	if want := (FileSetDiff{Added: []string{"added.patch"}, Removed: []string{"wave_fst_length_guard.patch"}}); !reflect.DeepEqual(d.Patches, want) {
+		t.Errorf("Patches = %+v, want %+v", d.Patches, want)
+	}
+	if want := (FileSetDiff{Changed: []string{"BUILD.bazel"}}); !reflect.DeepEqual(d.Overlay, want) {
+		t.Errorf("Overlay = %+v, want %+v", d.Overlay, want)
+	}
+	if len(d.SourceChanges) != 1 || d.SourceChanges[0].Field != "url" {
+		t.Errorf("SourceChanges = %v", d.SourceChanges)
+	}
+	if !strings.Contains(d.ModuleFileDiff, `+bazel_dep(name = "new", version = "2.0", dev_dependency = True)`) {
+		t.Errorf("ModuleFileDiff = %s", d.ModuleFileDiff)
+	}
+
+	var report strings.Builder
+	if err := writeVersionDiff(&report, d); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"nvc 1.22.0.bcr.1 -> 1.22.0.bcr.2\n",
+		"  + new 2.0 (dev)\n",
+		"  - gone 1.0\n",
+		"  ~ lib 1.0 -> 1.1\n",
+		"Patches:\n  + added.patch\n  - wave_fst_length_guard.patch\n",
+		"Overlay:\n  ~ BUILD.bazel\n",
+		"MODULE.bazel:\n",
+	} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("expected the report to contain %q, got:\n%s", want, report.String())
+		}
+	}
+
+	if !diffVersions(m.Name, to, to).Empty() {
+		t.Errorf("expected no changes between a version and itself")
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"sort"
+)
+
+// VersionPageData is the data for the detail page of a module version.
+type VersionPageData struct {
+	Title   string
+	Root    string
+	Site    SiteOptions
+	Module  Module
+	Version Version
+	// Dependents are the module versions in the registry that depend on
+	// this version, as "name@version".
+	Dependents []string
+	Patches    []CheckedFile
+	Overlay    []CheckedFile
+	// Diff holds the changes since the previous version, if there is one.
+	Diff *VersionDiff
+}
+
+// CheckedFile is a patch or overlay file of a version, together with the
+// integrity that source.json declares for it. A file that source.json
+// declares but that does not exist has no content, and a file that exists
+// but is not declared has no declared integrity.
+type CheckedFile struct {
+	Name      string
+	Content   string
+	Exists    bool
+	Declared  string
+	Integrity string
+}
+
+// Matches reports whether the file exists and its integrity is the declared
+// one.
+func (f CheckedFile) Matches() bool {
+	return f.Exists && f.Declared == f.Integrity
+}
+
+// versionPageLink is the link from the index page to the page of a module
+// version.
+func versionPageLink(module, version string) string {
+	return module + "/" + version + "/index.html"
+}
+
+// checkFiles pairs files with the integrities declared for them, sorted by
+// name.
+func checkFiles(files []File, declared map[string]string) []CheckedFile {
+	var result []CheckedFile
+	seen := make(map[string]bool)
+	for _, f := range files {
+		seen[f.Name] = true
+		result = append(result, CheckedFile{
+			Name:      f.Name,
+			Content:   f.Content,
+			Exists:    true,
+			Declared:  declared[f.Name],
+			Integrity: integrity([]byte(f.Content)),
+		})
+	}
+	for name, value := range declared {
+		if !seen[name] {
+			result = append(result, CheckedFile{Name: name, Declared: value})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// newVersionPageData returns the page data for version v of module m, which
+// follows the version previous, if not nil.
+func newVersionPageData(m Module, v Version, previous *Version, dependents []string) VersionPageData {
+	var diff *VersionDiff
+	if previous != nil {
+		d := diffVersions(m.Name, *previous, v)
+		diff = &d
+	}
+	return VersionPageData{
+		Title:      m.Name + "@" + v.Name,
+		Root:       "../../",
+		Module:     m,
+		Version:    v,
+		Dependents: dependents,
+		Patches:    checkFiles(v.Patches, v.Source.Patches),
+		Overlay:    checkFiles(v.Overlay, v.Source.Overlay),
+		Diff:       diff,
+	}
+}
+
+const versionTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+{{template "head" .}}
+</head>
+<body>
+    {{$module := .Module}}
+    {{$v := .Version}}
+    <div class="container">
+		<div class="d-flex justify-content-between align-items-center mt-5">
+			<h1 class="mb-0">
+				{{$module.Name}} <small class="text-muted">{{if isYanked $v.Name $module.Metadata}}<del>{{$v.Name}}</del>{{else}}{{$v.Name}}{{end}}</small>
+				{{if $v.Attestations}}<span class="badge bg-success fs-6"><i class="bi bi-patch-check"></i> provenance attested</span>{{end}}
+				{{if $v.Overlaid}}<span class="badge bg-warning text-dark fs-6">overlay</span>{{end}}
+			</h1>
+			{{template "themeToggle"}}
+		</div>
+		<p class="mt-2">
+			<a href="{{.Root}}index.html#card-{{sanitizeID $module.Name}}"><i class="bi bi-arrow-left"></i> All modules</a>
+			&middot;
+			<a href="../index.html#version-{{sanitizeID $v.Name}}">{{$module.Name}}</a>
+			&middot;
+			<a href="{{$.Site.BrowseURL}}/{{$module.Name}}/{{$v.Name}}" title="Browse the files"><i class="bi bi-github"></i></a>
+		</p>
+
+		{{if not $v.Published.IsZero}}
+			<p class="text-muted">Published {{template "timeAgo" $v.Published}}.</p>
+		{{end}}
+		{{with index $module.Metadata.YankedVersions $v.Name}}
+			<div class="alert alert-danger">Yanked: {{.}}</div>
+		{{end}}
+		{{if $module.Metadata.Deprecated}}
+			<div class="alert alert-secondary">The module is deprecated: {{$module.Metadata.Deprecated}}</div>
+		{{end}}
+
+		<h5>
+			Setup
+			<a href="#" onclick="copyToClipboard('{{ $.Site.Setup $module $v }}'); return false;" title="Copy the setup"><i class="bi bi-clipboard"></i></a>
+		</h5>
+		<pre><code>{{$.Site.Setup $module $v}}</code></pre>
+
+		<div class="row">
+			<div class="col-md-6">
+				<h5>Dependencies</h5>
+				{{if $v.Dependencies}}
+				<ul class="list-unstyled ms-2">
+					{{range $dep := $v.Dependencies}}
+					<li>
+						<code>{{$dep.Name}}</code> ({{$dep.Version}})
+						{{if $dep.DevDependency}}<span class="badge bg-secondary" style="font-size: 0.6em;">dev</span>{{end}}
+					</li>
+					{{end}}
+				</ul>
+				{{else}}
+				<p class="text-muted">None.</p>
+				{{end}}
+			</div>
+			<div class="col-md-6">
+				<h5>Used by</h5>
+				{{with .Dependents}}
+				<ul class="list-unstyled ms-2">
+					{{range $d := .}}<li><code>{{$d}}</code></li>{{end}}
+				</ul>
+				{{else}}
+				<p class="text-muted">No module in this registry.</p>
+				{{end}}
+			</div>
+		</div>
+
+		{{with .Diff}}
+		<h3 class="mt-4" id="changes">Changes since <a href="../{{.From}}/index.html">{{.From}}</a></h3>
+		{{template "versionDiff" .}}
+		{{end}}
+
+		<h3 class="mt-4">MODULE.bazel</h3>
+		<pre class="bg-body-tertiary p-2"><code>{{highlightStarlark $v.ModuleFile}}</code></pre>
+
+		<h3 class="mt-4">source.json</h3>
+		<table class="table table-sm">
+			<tr><th>URL</th><td><a href="{{$v.Source.URL}}">{{$v.Source.URL}}</a></td></tr>
+			<tr><th>Integrity</th><td><code>{{$v.Source.Integrity}}</code></td></tr>
+			{{with $v.Source.StripPrefix}}<tr><th>Strip prefix</th><td><code>{{.}}</code></td></tr>{{end}}
+			{{with $v.Source.PatchStrip}}<tr><th>Patch strip</th><td>{{.}}</td></tr>{{end}}
+			{{with $v.Source.DocsURL}}<tr><th>Docs</th><td><a href="{{.}}">{{.}}</a></td></tr>{{end}}
+		</table>
+		<details class="mb-3">
+			<summary>source.json</summary>
+			<pre class="bg-body-tertiary p-2"><code>{{$v.SourceFile}}</code></pre>
+		</details>
+
+		{{with .Patches}}
+		<h3 class="mt-4">Patches</h3>
+		{{range $p := .}}
+		<div class="card mb-3" id="patch-{{sanitizeID $p.Name}}">
+			<div class="card-header">
+				<strong>patches/{{$p.Name}}</strong>
+				{{template "integrity" $p}}
+			</div>
+			{{if $p.Exists}}
+			<pre class="mb-0 p-2"><code>{{highlightDiff $p.Content}}</code></pre>
+			{{end}}
+		</div>
+		{{end}}
+		{{end}}
+
+		{{with .Overlay}}
+		<h3 class="mt-4">Overlay</h3>
+		<table class="table table-sm">
+			<thead><tr><th>File</th><th>Integrity</th></tr></thead>
+			{{range $f := .}}
+			<tr>
+				<td><code>{{$f.Name}}</code></td>
+				<td>{{template "integrity" $f}}</td>
+			</tr>
+			{{end}}
+		</table>
+		{{end}}
+    </div>
+    {{template "bootstrapScript" .}}
+    {{template "footer" .}}
+</body>
+</html>
+
+{{define "versionDiff"}}
+	{{if .Empty}}
+		<p class="text-muted">No changes.</p>
+	{{else}}
+		<ul class="list-unstyled ms-2">
+			{{range .AddedDeps}}<li class="diff-add">Added dependency <code>{{.Name}}</code> ({{.Version}}){{if .DevDependency}} <span class="badge bg-secondary">dev</span>{{end}}</li>{{end}}
+			{{range .RemovedDeps}}<li class="diff-del">Removed dependency <code>{{.Name}}</code> ({{.Version}}){{if .DevDependency}} <span class="badge bg-secondary">dev</span>{{end}}</li>{{end}}
+			{{range .ChangedDeps}}<li>Changed dependency <code>{{.Name}}</code> from {{.From.Version}}{{if .From.DevDependency}} (dev){{end}} to {{.To.Version}}{{if .To.DevDependency}} (dev){{end}}</li>{{end}}
+			{{range .SourceChanges}}<li>Changed <code>{{.Field}}</code> in source.json from <code>{{.From}}</code> to <code>{{.To}}</code></li>{{end}}
+			{{range .Patches.Added}}<li class="diff-add">Added patch <code>{{.}}</code></li>{{end}}
+			{{range .Patches.Removed}}<li class="diff-del">Removed patch <code>{{.}}</code></li>{{end}}
+			{{range .Patches.Changed}}<li>Changed patch <code>{{.}}</code></li>{{end}}
+			{{range .Overlay.Added}}<li class="diff-add">Added overlay file <code>{{.}}</code></li>{{end}}
+			{{range .Overlay.Removed}}<li class="diff-del">Removed overlay file <code>{{.}}</code></li>{{end}}
+			{{range .Overlay.Changed}}<li>Changed overlay file <code>{{.}}</code></li>{{end}}
+		</ul>
+		{{with .ModuleFileDiff}}
+		<details class="mb-3">
+			<summary>MODULE.bazel diff</summary>
+			<pre class="bg-body-tertiary p-2"><code>{{highlightDiff .}}</code></pre>
+		</details>
+		{{end}}
+	{{end}}
+{{end}}
+
+{{define "integrity"}}
+	{{if .Matches}}
+		<code>{{.Integrity}}</code> <i class="bi bi-check-circle text-success" title="Matches source.json"></i>
+	{{else if not .Exists}}
+		<code>{{.Declared}}</code> <span class="badge bg-danger">missing</span>
+	{{else if not .Declared}}
+		<code>{{.Integrity}}</code> <span class="badge bg-danger">not in source.json</span>
+	{{else}}
+		<code>{{.Integrity}}</code> <span class="badge bg-danger" title="source.json declares {{.Declared}}">integrity mismatch</span>
+	{{end}}
+{{end}}
+`
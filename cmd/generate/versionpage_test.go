@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckFiles(t *testing.T) {
+	files := []File{
+		{Name: "b.patch", Content: "b"},
+		{Name: "a.patch", Content: "a"},
+		{Name: "extra.patch", Content: "extra"},
+	}
+	declared := map[string]string{
+		"a.patch":       integrity([]byte("a")),
+		"b.patch":       integrity([]byte("not b")),
+		"missing.patch": integrity([]byte("missing")),
+	}
+	got := checkFiles(files, declared)
+
+	var names []string
+	var matches []bool
+	for _, f := range got {
+		names = append(names, f.Name)
+		matches = append(matches, f.Matches())
+	}
+	if want := []string{"a.patch", "b.patch", "extra.patch", "missing.patch"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("checkFiles() names = %v, want %v", names, want)
+	}
+	if want := []bool{true, false, false, false}; !reflect.DeepEqual(matches, want) {
+		t.Errorf("checkFiles() matches = %v, want %v", matches, want)
+	}
+	if got[3].Exists {
+		t.Errorf("expected missing.patch not to exist")
+	}
+}
+
+func TestWriteVersionPages(t *testing.T) {
+	modulesDir := t.TempDir()
+	writeTestModule(t, modulesDir, "lib", map[string]string{
+		"1.0.0": `module(name = "lib", version = "1.0.0")`,
+	})
+	patch := "--- a/BUILD\n+++ b/BUILD\n@@ -1 +1 @@\n-old\n+new\n"
+	writeTestFile(t, filepath.Join(modulesDir, "lib", "1.0.0", "patches", "fix.patch"), patch)
+	writeTestFile(t, filepath.Join(modulesDir, "lib", "1.0.0", "overlay", "sub", "BUILD.bazel"), "# overlay\n")
+	source := Source{
+		Integrity: "sha256-r8OAAxBZdAzdRnKhwxb7gIHvlrERw7yEyMf3My1SJbo=",
+		URL:       "https://example.com/lib-1.0.0.tar.gz",
+		Patches:   map[string]string{"fix.patch": integrity([]byte(patch))},
+		Overlay:   map[string]string{"sub/BUILD.bazel": integrity([]byte("# stale\n"))},
+	}
+	sourceJSON, err := marshalJSON(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(modulesDir, "lib", "1.0.0", "source.json"), string(sourceJSON))
+	modules, err := findModules(modulesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputDir := t.TempDir()
+	if err := writeModulePages(testTemplates(t, ""), modules, nil, outputDir, SiteOptions{}); err != nil {
+		t.Fatalf("writeModulePages failed: %v", err)
+	}
+	page, err := os.ReadFile(filepath.Join(outputDir, "lib", "1.0.0", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<title>lib@1.0.0 - Bazel Registry</title>`,
+		`href="../../hdlfactory.png"`,
+		`<span class="hl-call">module</span>`,
+		`<span class="diff-add">+new</span>`,
+		`<code>sub/BUILD.bazel</code>`,
+		`integrity mismatch`,
+		source.URL,
+	} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("expected the version page to contain %q", want)
+		}
+	}
+}
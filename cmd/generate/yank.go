@@ -0,0 +1,130 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+)
+
+func runYank(args []string) error {
+	var modulesDir, module, version, reason string
+	fs := flag.NewFlagSet("yank", flag.ExitOnError)
+	fs.StringVar(&modulesDir, "modules_dir", "modules", "The path to the modules directory.")
+	fs.StringVar(&module, "module", "", "The name of the module.")
+	fs.StringVar(&version, "version", "", "The version to yank.")
+	fs.StringVar(&reason, "reason", "", "Why the version is yanked. Shown to users of the version.")
+	fs.Parse(args)
+	for name, value := range map[string]string{"module": module, "version": version, "reason": reason} {
+		if value == "" {
+			return fmt.Errorf("flag --%s=... is required", name)
+		}
+	}
+	if err := yank(modulesDir, module, version, reason); err != nil {
+		return err
+	}
+	dependents, err := findDependents(modulesDir, module, version)
+	if err != nil {
+		return err
+	}
+	for _, d := range dependents {
+		log.Printf("warning: %s@%s is still depended upon by %s", module, version, d)
+	}
+	return nil
+}
+
+func runUnyank(args []string) error {
+	var modulesDir, module, version string
+	fs := flag.NewFlagSet("unyank", flag.ExitOnError)
+	fs.StringVar(&modulesDir, "modules_dir", "modules", "The path to the modules directory.")
+	fs.StringVar(&module, "module", "", "The name of the module.")
+	fs.StringVar(&version, "version", "", "The version to unyank.")
+	fs.Parse(args)
+	for name, value := range map[string]string{"module": module, "version": version} {
+		if value == "" {
+			return fmt.Errorf("flag --%s=... is required", name)
+		}
+	}
+	return unyank(modulesDir, module, version)
+}
+
+// yank marks a version of a module as yanked in its metadata.json.
+func yank(modulesDir, module, version, reason string) error {
+	m, err := loadModule(modulesDir, module)
+	if err != nil {
+		return fmt.Errorf("module %s: %w", module, err)
+	}
+	found := false
+	for _, v := range m.Versions {
+		found = found || v.Name == version
+	}
+	if !found {
+		return fmt.Errorf("module %s has no version %s", module, version)
+	}
+	return updateYankedVersions(modulesDir, module, func(yanked *jsonObject) error {
+		if yanked.Has(version) {
+			return fmt.Errorf("%s@%s is already yanked", module, version)
+		}
+		return yanked.Set(version, reason)
+	})
+}
+
+// unyank removes the yanked mark from a version of a module.
+func unyank(modulesDir, module, version string) error {
+	return updateYankedVersions(modulesDir, module, func(yanked *jsonObject) error {
+		if !yanked.Has(version) {
+			return fmt.Errorf("%s@%s is not yanked", module, version)
+		}
+		yanked.Delete(version)
+		return nil
+	})
+}
+
+// updateYankedVersions applies update to the yanked_versions of a module,
+// keeping the rest of metadata.json as it is.
+func updateYankedVersions(modulesDir, module string, update func(*jsonObject) error) error {
+	metadataPath := filepath.Join(modulesDir, module, "metadata.json")
+	metadata, err := readJSONObject(metadataPath)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata for module %q: %w", module, err)
+	}
+	yanked := &jsonObject{}
+	if metadata.Has("yanked_versions") {
+		if err := metadata.Get("yanked_versions", yanked); err != nil {
+			return err
+		}
+	}
+	if err := update(yanked); err != nil {
+		return err
+	}
+	if err := metadata.Set("yanked_versions", yanked); err != nil {
+		return err
+	}
+	return writeJSONObject(metadataPath, metadata)
+}
+
+// findDependents returns the non-yanked module versions in the registry
+// that depend on module@version, as "name@version".
+func findDependents(modulesDir, module, version string) ([]string, error) {
+	modules, err := findModules(modulesDir)
+	if err != nil {
+		return nil, err
+	}
+	var dependents []string
+	for _, m := range modules {
+		if m.Name == module {
+			continue
+		}
+		for _, v := range m.Versions {
+			if _, ok := m.Metadata.YankedVersions[v.Name]; ok {
+				continue
+			}
+			for _, dep := range v.Dependencies {
+				if dep.Name == module && dep.Version == version {
+					dependents = append(dependents, m.Name+"@"+v.Name)
+				}
+			}
+		}
+	}
+	return dependents, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testSource(name, version string) string {
+	return `{"integrity": "sha256-r8OAAxBZdAzdRnKhwxb7gIHvlrERw7yEyMf3My1SJbo=", "url": "https://example.com/` + name + "-" + version + `.tar.gz"}`
+}
+
+func writeTestModule(t *testing.T, modulesDir, name string, versions map[string]string) {
+	t.Helper()
+	var names []string
+	for v, moduleFile := range versions {
+		names = append(names, v)
+		writeTestFile(t, filepath.Join(modulesDir, name, v, "MODULE.bazel"), moduleFile)
+		writeTestFile(t, filepath.Join(modulesDir, name, v, "source.json"), testSource(name, v))
+	}
+	sortVersions(names)
+	metadata, err := marshalJSON(Metadata{
+		Homepage:       "https://example.com/" + name,
+		Maintainers:    []Maintainer{{GitHub: "example"}},
+		Repo:           []string{"github:example/" + name},
+		Versions:       names,
+		YankedVersions: map[string]string{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(modulesDir, name, "metadata.json"), string(metadata))
+}
+
+func TestYankAndUnyank(t *testing.T) {
+	modulesDir := t.TempDir()
+	writeTestModule(t, modulesDir, "lib", map[string]string{
+		"1.0.0": `module(name = "lib", version = "1.0.0")`,
+		"1.1.0": `module(name = "lib", version = "1.1.0")`,
+	})
+	writeTestModule(t, modulesDir, "app", map[string]string{
+		"2.0.0": `module(name = "app", version = "2.0.0")` + "\n" + `bazel_dep(name = "lib", version = "1.0.0")`,
+	})
+	metadataPath := filepath.Join(modulesDir, "lib", "metadata.json")
+	original, _ := os.ReadFile(metadataPath)
+
+	if err := yank(modulesDir, "lib", "3.0.0", "nope"); err == nil {
+		t.Errorf("expected an error when yanking a non-existent version")
+	}
+	if err := yank(modulesDir, "lib", "1.0.0", "broken"); err != nil {
+		t.Fatalf("yank failed: %v", err)
+	}
+	if err := yank(modulesDir, "lib", "1.0.0", "broken"); err == nil {
+		t.Errorf("expected an error when yanking twice")
+	}
+	m, err := loadModule(modulesDir, "lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"1.0.0": "broken"}; !reflect.DeepEqual(m.Metadata.YankedVersions, want) {
+		t.Errorf("yanked versions = %v, want %v", m.Metadata.YankedVersions, want)
+	}
+
+	dependents, err := findDependents(modulesDir, "lib", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"app@2.0.0"}; !reflect.DeepEqual(dependents, want) {
+		t.Errorf("dependents = %v, want %v", dependents, want)
+	}
+
+	if err := unyank(modulesDir, "lib", "1.0.0"); err != nil {
+		t.Fatalf("unyank failed: %v", err)
+	}
+	if err := unyank(modulesDir, "lib", "1.0.0"); err == nil {
+		t.Errorf("expected an error when unyanking a version that is not yanked")
+	}
+	if got, _ := os.ReadFile(metadataPath); string(got) != string(original) {
+		t.Errorf("metadata.json changed after yank and unyank:\n%s\nwant:\n%s", got, original)
+	}
+}
@@ -0,0 +1,10 @@
+// Package web holds the pinned, unmodified web assets that the generated
+// pages load from a CDN, for the pages that load nothing from other sites.
+package web
+
+import "embed"
+
+// FS holds the assets, at their paths in the upstream distributions.
+//
+//go:embed bootstrap/dist bootstrap-icons/font
+var FS embed.FS